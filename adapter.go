@@ -4,8 +4,8 @@ package slack
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
-	"sync"
 
 	"github.com/go-joe/joe"
 	"github.com/go-joe/joe/reactions"
@@ -20,30 +20,71 @@ type BotAdapter struct {
 	logger  *zap.Logger
 	name    string
 	userID  string
+	teamID  string
+
+	// enterpriseID is only set if the workspace of the adapter belongs to an
+	// Enterprise Grid organization.
+	enterpriseID string
 
 	logUnknownMessageTypes bool
 	listenPassive          bool
+	threadedResponses      bool
+	replyBroadcast         bool
+	receiveEditedMessages  bool
+	messageMetadata        bool
+
+	sampler *eventSampler // nil if disabled
+	filter  *eventFilter  // nil if all events are allowed
+
+	botMessages *botMessageFilter // nil if bot messages are not filtered
 
 	sendMsgParams slack.PostMessageParameters
+	longMessages  LongMessageConfig
 
 	slack  slackAPI
 	rtm    slackRTM
 	events chan slackEvent
 
-	usersMu sync.RWMutex
-	users   map[string]joe.User
+	users       UserCache
+	userStats   *userCacheCounters // shared with the adapters of all workspaces
+	channels    *channelCache
+	sharedFiles *sharedFiles
+	outbound    *outboundQueue // nil if disabled
+	workspaces  *workspaces    // nil unless an installation store is configured
 }
 
 type slackEvent struct {
 	Type string
 	Data interface{}
+
+	// The workspace that sent the event. This is only set by adapters that
+	// receive events from Slack apps which can be installed in multiple
+	// workspaces.
+	TeamID       string
+	EnterpriseID string
+
+	// ChannelType is the type of the conversation of a message event if it is
+	// known from the event.
+	ChannelType ConversationType
 }
 
 type slackAPI interface {
 	AuthTestContext(context.Context) (*slack.AuthTestResponse, error)
 	PostMessageContext(ctx context.Context, channelID string, opts ...slack.MsgOption) (respChannel, respTimestamp string, err error)
+	UpdateMessageContext(ctx context.Context, channelID, timestamp string, opts ...slack.MsgOption) (respChannel, respTimestamp, text string, err error)
+	DeleteMessageContext(ctx context.Context, channelID, timestamp string) (respChannel, respTimestamp string, err error)
+	PostEphemeralContext(ctx context.Context, channelID, userID string, opts ...slack.MsgOption) (timestamp string, err error)
 	AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error
-	GetUserInfo(user string) (*slack.User, error)
+	RemoveReactionContext(ctx context.Context, name string, item slack.ItemRef) error
+	GetUserInfoContext(ctx context.Context, user string) (*slack.User, error)
+	GetUsersContext(ctx context.Context) ([]slack.User, error)
+	GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error)
+	GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) (channels []slack.Channel, nextCursor string, err error)
+	OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (channel *slack.Channel, noOp, alreadyOpen bool, err error)
+	UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (file *slack.File, err error)
+	GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error)
+	GetFile(downloadURL string, writer io.Writer) error
+	GetBotInfoContext(ctx context.Context, bot string) (*slack.Bot, error)
 }
 
 type slackRTM interface {
@@ -53,8 +94,11 @@ type slackRTM interface {
 // Adapter returns a new BotAdapter as joe.Module.
 //
 // Apart from the typical joe.ReceiveMessageEvent event, this adapter also emits
-// the joe.UserTypingEvent. The ReceiveMessageEvent.Data field is always a
-// pointer to the corresponding github.com/slack-go/slack.MessageEvent instance.
+// the joe.UserTypingEvent. The ReceiveMessageEvent.Data field is a pointer to
+// the corresponding github.com/slack-go/slack.MessageEvent instance, or an
+// *EnrichedMessageEvent that wraps it if the WithMessageMetadata() option is
+// used. Use MessageEventFromData(…) to get the *slack.MessageEvent in both
+// cases. If the message was sent in a thread, its ThreadTimestamp field is set.
 func Adapter(token string, opts ...Option) joe.Module {
 	return joe.ModuleFunc(func(joeConf *joe.Config) error {
 		conf, err := newConf(token, joeConf, opts)
@@ -120,6 +164,7 @@ func NewAdapter(ctx context.Context, conf Config) (*BotAdapter, error) {
 	go func() {
 		defer close(events)
 		for evt := range rtm.IncomingEvents {
+			a.sampler.sampleRaw(evt.Type, evt.Data)
 			events <- slackEvent{
 				Type: evt.Type,
 				Data: evt.Data,
@@ -143,27 +188,79 @@ func newAdapter(ctx context.Context, client slackAPI, rtm slackRTM, events chan
 		logger:        conf.Logger,
 		name:          conf.Name,
 		sendMsgParams: conf.SendMsgParams,
-		users:         map[string]joe.User{}, // TODO: cache expiration?
+		longMessages:  conf.LongMessages,
+		users:         conf.UserCache,
+		userStats:     new(userCacheCounters),
+		channels:      newChannelCache(defaultChannelCacheTTL),
+		sharedFiles:   newSharedFiles(sharedFilesWindow),
 		listenPassive: conf.ListenPassive,
+
+		logUnknownMessageTypes: conf.LogUnknownMessageTypes,
+
+		threadedResponses: conf.ThreadedResponses,
+		replyBroadcast:    conf.ReplyBroadcast,
+
+		receiveEditedMessages: conf.ReceiveEditedMessages,
+		messageMetadata:       conf.MessageMetadata,
 	}
 
 	if a.logger == nil {
 		a.logger = zap.NewNop()
 	}
 
-	resp, err := client.AuthTestContext(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("slack auth test failed: %w", err)
+	if a.users == nil {
+		a.users = NewUserCache(defaultUserCacheSize, defaultUserCacheTTL)
 	}
 
-	a.userID = resp.UserID
-	a.logger.Info("Connected to slack API",
-		zap.String("url", resp.URL),
-		zap.String("user", resp.User),
-		zap.String("user_id", resp.UserID),
-		zap.String("team", resp.Team),
-		zap.String("team_id", resp.TeamID),
-	)
+	a.sampler = newEventSampler(conf.EventSampling, a.logger)
+	a.filter = newEventFilter(conf.Filter)
+	a.botMessages = newBotMessageFilter(conf.BotMessages)
+
+	if a.longMessages.MaxLength == 0 {
+		a.longMessages.MaxLength = defaultMaxMessageLength
+	}
+
+	if conf.RateLimit.Enabled {
+		a.slack = newRateLimitedAPI(client, conf.RateLimit, a.logger)
+	}
+
+	// If the app is distributed to multiple workspaces, the adapter does not
+	// need a token of its own.
+	store := conf.EventsAPI.Installations
+	if conf.Token != "" || store == nil {
+		resp, err := client.AuthTestContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("slack auth test failed: %w", err)
+		}
+
+		a.userID = resp.UserID
+		a.teamID = resp.TeamID
+		a.enterpriseID = resp.EnterpriseID
+		a.logger.Info("Connected to slack API",
+			zap.String("url", resp.URL),
+			zap.String("user", resp.User),
+			zap.String("user_id", resp.UserID),
+			zap.String("team", resp.Team),
+			zap.String("team_id", resp.TeamID),
+			zap.String("enterprise_id", resp.EnterpriseID),
+		)
+	}
+
+	if store != nil {
+		a.enableWorkspaces(store, conf)
+	}
+
+	if conf.PrewarmUserCache {
+		go a.prewarmUserCache(conf.UserCacheRefreshInterval)
+	}
+
+	if conf.OutboundQueue.Store != nil {
+		a.outbound = newOutboundQueue(ctx, conf.OutboundQueue, a.sendNow, a.logger)
+		err := a.outbound.start()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return a, nil
 }
@@ -175,7 +272,17 @@ func (a *BotAdapter) RegisterAt(brain *joe.Brain) {
 }
 
 func (a *BotAdapter) handleSlackEvents(brain *joe.Brain) {
-	for msg := range a.events {
+	parent := a
+	for msg := range parent.events {
+		a := parent.adapterForEvent(msg)
+		if a == nil {
+			continue
+		}
+
+		if msg.ChannelType != "" {
+			a.channels.setType(eventChannel(msg.Data), msg.ChannelType)
+		}
+
 		switch ev := msg.Data.(type) {
 		case *slack.MessageEvent:
 			a.handleMessageEvent(ev, brain)
@@ -183,6 +290,43 @@ func (a *BotAdapter) handleSlackEvents(brain *joe.Brain) {
 		case *slack.ReactionAddedEvent:
 			a.handleReactionAddedEvent(ev, brain)
 
+		case *slack.ReactionRemovedEvent:
+			a.handleReactionRemovedEvent(ev, brain)
+
+		case *slack.UserChangeEvent:
+			a.handleUserChangeEvent(ev)
+
+		case *slack.FileSharedEvent:
+			a.handleFileSharedEvent(ev, brain)
+
+		case *slack.MemberJoinedChannelEvent:
+			a.handleMemberJoinedChannelEvent(ev, brain)
+
+		case *slack.MemberLeftChannelEvent:
+			a.handleMemberLeftChannelEvent(ev, brain)
+
+		case *slack.ChannelJoinedEvent:
+			a.handleChannelJoinedEvent(ev, brain)
+
+		case *slack.GroupJoinedEvent:
+			// private channels are called groups in the RTM API
+			a.handleChannelJoinedEvent((*slack.ChannelJoinedEvent)(ev), brain)
+
+		case *slack.ChannelLeftEvent:
+			a.handleChannelLeftEvent(ev, brain)
+
+		case *slack.GroupLeftEvent:
+			a.handleChannelLeftEvent((*slack.ChannelLeftEvent)(ev), brain)
+
+		case *slack.TeamJoinEvent:
+			a.handleTeamJoinEvent(ev, brain)
+
+		case *interactionEvent:
+			a.handleInteraction(ev, brain)
+
+		case *slack.SlashCommand:
+			a.handleSlashCommand(ev, brain)
+
 		case *slack.RTMError:
 			a.logger.Error("Slack Real Time Messaging (RTM) error",
 				zap.Int("code", ev.Code),
@@ -209,6 +353,7 @@ func (a *BotAdapter) handleSlackEvents(brain *joe.Brain) {
 			}
 
 		default:
+			a.sampler.sampleUnknown(msg.Type, msg.Data)
 			if a.logUnknownMessageTypes {
 				a.logger.Error("Received unknown type from Real Time Messaging (RTM) system",
 					zap.String("type", msg.Type),
@@ -221,27 +366,66 @@ func (a *BotAdapter) handleSlackEvents(brain *joe.Brain) {
 }
 
 func (a *BotAdapter) handleMessageEvent(ev *slack.MessageEvent, brain joe.EventEmitter) {
+	switch ev.SubType {
+	case "message_changed":
+		a.handleMessageChangedEvent(ev, brain)
+		return
+	case "message_deleted":
+		a.handleMessageDeletedEvent(ev, brain)
+		return
+	}
+
 	// check if the message comes from ourselves
 	if ev.User == a.userID {
 		// msg is from us, ignore it!
 		return
 	}
 
+	if !a.allowBotMessage(ev) || !a.allowEvent(ev.Channel, ev.User) {
+		return
+	}
+
+	if ev.SubType == "file_share" {
+		a.handleFileShareMessage(ev, brain)
+	}
+
 	// check if we have a DM, or standard channel post
 	selfLink := a.userLink(a.userID)
-	direct := strings.HasPrefix(ev.Msg.Channel, "D")
-	if !direct && !strings.Contains(ev.Msg.Text, selfLink) && !a.listenPassive {
+	if !a.listenPassive && !strings.Contains(ev.Msg.Text, selfLink) && !a.isDirectMessage(ev) {
 		// msg not for us!
 		return
 	}
 
+	if !a.allowBotReply(ev) {
+		return
+	}
+
+	channel := ev.Channel
+	if a.threadedResponses {
+		threadTS := ev.ThreadTimestamp
+		if threadTS == "" {
+			threadTS = ev.Timestamp
+		}
+		channel = threadChannel(ev.Channel, threadTS)
+	}
+
+	if ev.Team == "" {
+		// Messages of the Events API do not contain the team.
+		ev.Team = a.teamID
+	}
+
+	var data interface{} = ev
+	if a.messageMetadata {
+		data = a.enrichMessageEvent(ev)
+	}
+
 	text := strings.TrimSpace(strings.TrimPrefix(ev.Msg.Text, selfLink))
 	brain.Emit(joe.ReceiveMessageEvent{
 		Text:     text,
-		Channel:  ev.Channel,
+		Channel:  channel,
 		ID:       ev.Timestamp, // slack uses the message timestamps as identifiers within the channel
 		AuthorID: ev.User,
-		Data:     ev,
+		Data:     data,
 	})
 }
 
@@ -257,6 +441,10 @@ func (a *BotAdapter) handleReactionAddedEvent(ev *slack.ReactionAddedEvent, brai
 		return
 	}
 
+	if !a.allowEvent(ev.Item.Channel, ev.User) {
+		return
+	}
+
 	brain.Emit(reactions.Event{
 		Channel:   ev.Item.Channel,
 		MessageID: ev.Item.Timestamp,
@@ -265,61 +453,292 @@ func (a *BotAdapter) handleReactionAddedEvent(ev *slack.ReactionAddedEvent, brai
 	})
 }
 
-func (a *BotAdapter) userByID(userID string) joe.User {
-	a.usersMu.RLock()
-	user, ok := a.users[userID]
-	a.usersMu.RUnlock()
+// The ReactionRemovedEvent is emitted when a user removes a reaction from a
+// message. It carries the same fields as the reactions.Event that is emitted
+// when the reaction was added.
+type ReactionRemovedEvent struct {
+	Reaction     reactions.Reaction
+	MessageID    string
+	Channel      string
+	AuthorID     string
+	TeamID       string
+	EnterpriseID string
+}
+
+// See https://api.slack.com/events/reaction_removed
+func (a *BotAdapter) handleReactionRemovedEvent(ev *slack.ReactionRemovedEvent, brain joe.EventEmitter) {
+	if ev.User == a.userID {
+		// reaction is from us, ignore it!
+		return
+	}
+
+	if ev.Item.Type != "message" {
+		// reactions for other things except messages is not supported by Joe
+		return
+	}
 
-	if ok {
-		return user
+	if !a.allowEvent(ev.Item.Channel, ev.User) {
+		return
 	}
 
-	resp, err := a.slack.GetUserInfo(userID)
+	brain.Emit(ReactionRemovedEvent{
+		Channel:      ev.Item.Channel,
+		MessageID:    ev.Item.Timestamp,
+		AuthorID:     ev.User,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		Reaction:     reactions.Reaction{Shortcode: ev.Reaction},
+	})
+}
+
+func (a *BotAdapter) userByID(userID string) joe.User {
+	user, err := a.slackUser(userID)
 	if err != nil {
 		a.logger.Error("Failed to get user info by ID",
 			zap.String("user_id", userID),
+			zap.Error(err),
 		)
 		return joe.User{ID: userID}
 	}
 
-	user = joe.User{
-		ID:       resp.ID,
-		Name:     resp.Name,
-		RealName: resp.RealName,
+	return joe.User{
+		ID:       user.ID,
+		Name:     user.Name,
+		RealName: user.RealName,
 	}
-
-	a.usersMu.Lock()
-	a.users[userID] = user
-	a.usersMu.Unlock()
-
-	return user
 }
 
 // Send implements joe.Adapter by sending all received text messages to the
-// given slack channel ID.
+// given slack channel ID. If the channel contains a thread timestamp (see
+// WithThreadedResponses), the message is sent to this thread.
+//
+// Instead of a channel ID, the channel can also be given by name (e.g.
+// "#deployments") or as user name (e.g. "@jd") to send a direct message.
+//
+// Long messages can be split into multiple messages (see WithMessageSplitting)
+// or uploaded as snippet (see WithLongMessagesAsSnippet).
+//
+// If the outbound queue is enabled (see WithOutboundQueue), the message is
+// only stored and sent asynchronously.
 func (a *BotAdapter) Send(text, channelID string) error {
+	parts := a.messageParts(text)
+	for i, part := range parts {
+		var err error
+		if a.outbound != nil {
+			err = a.outbound.enqueue(part, channelID)
+		} else {
+			err = a.sendNow(part, channelID)
+		}
+
+		if err != nil && len(parts) == 1 {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to send part %d of %d: %w", i+1, len(parts), err)
+		}
+	}
+
+	return nil
+}
+
+func (a *BotAdapter) sendNow(text, channelID string) error {
+	if a.isLongMessage(text) {
+		return a.sendSnippet(text, channelID)
+	}
+
+	_, err := a.SendWithID(text, channelID)
+	return err
+}
+
+// SendWithID sends a text message to the given slack channel ID just like
+// Send(…) but additionally returns the ID (i.e. timestamp) of the new message.
+// The ID can be used to update or delete the message later.
+func (a *BotAdapter) SendWithID(text, channelID string) (msgID string, err error) {
+	channelID, threadTS := splitThreadChannel(channelID)
+	if threadTS != "" {
+		return a.sendInThread(text, channelID, threadTS)
+	}
+
+	return a.send(channelID, slack.MsgOptionText(text, false))
+}
+
+// SendInThread sends a text message as reply to the thread with the given
+// timestamp. The thread timestamp is the timestamp of the thread's parent
+// message.
+func (a *BotAdapter) SendInThread(text, channelID, threadTS string) error {
+	_, err := a.sendInThread(text, channelID, threadTS)
+	return err
+}
+
+func (a *BotAdapter) sendInThread(text, channelID, threadTS string) (string, error) {
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(threadTS),
+	}
+
+	if a.replyBroadcast {
+		opts = append(opts, slack.MsgOptionBroadcast())
+	}
+
+	return a.send(channelID, opts...)
+}
+
+// ReplyInThread sends a text message as reply to the given message. If the
+// message was sent in a thread, the reply is sent to the same thread, otherwise
+// a new thread is started on the message.
+func (a *BotAdapter) ReplyInThread(msg joe.Message, text string) error {
+	channelID, threadTS := splitThreadChannel(msg.Channel)
+	if ev, ok := MessageEventFromData(msg.Data); ok && threadTS == "" {
+		threadTS = ev.ThreadTimestamp
+	}
+	if threadTS == "" {
+		threadTS = msg.ID
+	}
+
+	return a.SendInThread(text, channelID, threadTS)
+}
+
+// send posts a message with the given options and the default message
+// parameters of the adapter and returns the timestamp of the new message.
+func (a *BotAdapter) send(channelID string, opts ...slack.MsgOption) (string, error) {
+	sender, err := a.senderFor(channelID)
+	if err != nil {
+		return "", err
+	}
+	if sender != a {
+		return sender.send(channelID, opts...)
+	}
+
+	channelID, err = a.resolveChannel(channelID)
+	if err != nil {
+		return "", err
+	}
+
 	a.logger.Info("Sending message to channel",
 		zap.String("channel_id", channelID),
 		// do not leak actual message content since it might be sensitive
 	)
 
-	_, _, err := a.slack.PostMessageContext(a.context, channelID,
-		slack.MsgOptionText(text, false),
+	opts = append(opts, a.defaultMsgOptions()...)
+	_, timestamp, err := a.slack.PostMessageContext(a.context, channelID, opts...)
+	return timestamp, err
+}
+
+// defaultMsgOptions returns the options that are applied to all messages that
+// are sent by the adapter.
+func (a *BotAdapter) defaultMsgOptions() []slack.MsgOption {
+	return []slack.MsgOption{
 		slack.MsgOptionPostMessageParameters(a.sendMsgParams),
 		slack.MsgOptionUser(a.userID),
 		slack.MsgOptionUsername(a.name),
+	}
+}
+
+// SendEphemeral sends a text message to the given channel that is only visible
+// to the user with the given ID. The user must be a member of the channel. If
+// the channel contains a thread timestamp, the message is shown in this thread.
+func (a *BotAdapter) SendEphemeral(text, channelID, userID string) error {
+	id, threadTS := splitThreadChannel(channelID)
+	sender, err := a.senderFor(id)
+	if err != nil {
+		return err
+	}
+	if sender != a {
+		return sender.SendEphemeral(text, channelID, userID)
+	}
+
+	channelID, err = a.resolveChannel(id)
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info("Sending ephemeral message to channel",
+		zap.String("channel_id", channelID),
+		zap.String("user_id", userID),
 	)
 
-	return err
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+
+	opts = append(opts, a.defaultMsgOptions()...)
+	_, err = a.slack.PostEphemeralContext(a.context, channelID, userID, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to send ephemeral message: %w", err)
+	}
+
+	return nil
+}
+
+// RespondEphemeral sends a text message to the channel of the given message
+// that is only visible to the author of the message. This is useful for
+// responses that are not interesting for other channel members such as help
+// texts or error messages.
+func (a *BotAdapter) RespondEphemeral(msg joe.Message, text string) error {
+	return a.SendEphemeral(text, msg.Channel, msg.AuthorID)
+}
+
+// Update replaces the text of a message that was sent by the bot before.
+// See SendWithID(…) to retrieve the ID of a new message.
+func (a *BotAdapter) Update(channelID, msgID, text string) error {
+	channelID, _ = splitThreadChannel(channelID)
+	channelID, err := a.resolveChannel(channelID)
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info("Updating message",
+		zap.String("channel_id", channelID),
+		zap.String("msg_id", msgID),
+	)
+
+	_, _, _, err = a.slack.UpdateMessageContext(a.context, channelID, msgID, slack.MsgOptionText(text, false))
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a message that was sent by the bot before.
+// See SendWithID(…) to retrieve the ID of a new message.
+func (a *BotAdapter) Delete(channelID, msgID string) error {
+	channelID, _ = splitThreadChannel(channelID)
+	channelID, err := a.resolveChannel(channelID)
+	if err != nil {
+		return err
+	}
+
+	a.logger.Info("Deleting message",
+		zap.String("channel_id", channelID),
+		zap.String("msg_id", msgID),
+	)
+
+	_, _, err = a.slack.DeleteMessageContext(a.context, channelID, msgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	return nil
 }
 
 // React implements joe.ReactionAwareAdapter by letting the bot attach the given
 // reaction to the message.
 func (a *BotAdapter) React(reaction reactions.Reaction, msg joe.Message) error {
-	ref := slack.NewRefToMessage(msg.Channel, msg.ID)
+	channelID, _ := splitThreadChannel(msg.Channel)
+	ref := slack.NewRefToMessage(channelID, msg.ID)
 	return a.slack.AddReactionContext(a.context, reaction.Shortcode, ref)
 }
 
+// Unreact removes the given reaction that the bot has attached to the message
+// before (see React(…)).
+func (a *BotAdapter) Unreact(reaction reactions.Reaction, msg joe.Message) error {
+	channelID, _ := splitThreadChannel(msg.Channel)
+	ref := slack.NewRefToMessage(channelID, msg.ID)
+	return a.slack.RemoveReactionContext(a.context, reaction.Shortcode, ref)
+}
+
 // Close disconnects the adapter from the slack API.
 func (a *BotAdapter) Close() error {
 	if a.rtm != nil {
@@ -335,3 +754,20 @@ func (a *BotAdapter) Close() error {
 func (a *BotAdapter) userLink(userID string) string {
 	return fmt.Sprintf("<@%s>", userID)
 }
+
+// threadChannel encodes a channel ID and a thread timestamp into a single
+// string so it can be passed via the Channel field of joe messages.
+func threadChannel(channelID, threadTS string) string {
+	return channelID + "/" + threadTS
+}
+
+// splitThreadChannel is the inverse of threadChannel. The returned thread
+// timestamp is empty if the channel does not contain any.
+func splitThreadChannel(channel string) (channelID, threadTS string) {
+	i := strings.IndexByte(channel, '/')
+	if i < 0 {
+		return channel, ""
+	}
+
+	return channel[:i], channel[i+1:]
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"testing"
 
 	"github.com/go-joe/joe"
@@ -67,7 +69,8 @@ func TestNewAdapter_ErrorWrap(t *testing.T) {
 
 func TestAdapter_IgnoreNormalMessages(t *testing.T) {
 	brain := joetest.NewBrain(t)
-	a, _ := newTestAdapter(t)
+	a, slackAPI := newTestAdapter(t)
+	expectConversation(slackAPI, a.context, "C1H9RESGL", false)
 
 	done := make(chan bool)
 	go func() {
@@ -91,7 +94,8 @@ func TestAdapter_IgnoreNormalMessages(t *testing.T) {
 
 func TestAdapter_DirectMessages(t *testing.T) {
 	brain := joetest.NewBrain(t)
-	a, _ := newTestAdapter(t)
+	a, slackAPI := newTestAdapter(t)
+	expectConversation(slackAPI, a.context, "D023BB3L2", true)
 
 	done := make(chan bool)
 	go func() {
@@ -222,6 +226,234 @@ func TestAdapter_Send(t *testing.T) {
 	slackAPI.AssertExpectations(t)
 }
 
+func TestAdapter_SendWithID(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	expectPostMessage(slackAPI, a.context, "C1H9RESGL", 4).
+		Return("C1H9RESGL", "1360782400.498405", nil)
+
+	msgID, err := a.SendWithID("Deploying…", "C1H9RESGL")
+	require.NoError(t, err)
+	assert.Equal(t, "1360782400.498405", msgID)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_Update(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	var text string
+	slackAPI.On("UpdateMessageContext", a.context, "C1H9RESGL", "1360782400.498405",
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionText
+	).Run(func(args mock.Arguments) {
+		_, values, err := slack.UnsafeApplyMsgOptions("", "", "", args.Get(3).(slack.MsgOption))
+		require.NoError(t, err)
+		text = values.Get("text")
+	}).Return("C1H9RESGL", "1360782400.498405", "Deployed", nil)
+
+	err := a.Update("C1H9RESGL/1360782300.498405", "1360782400.498405", "Deployed")
+	require.NoError(t, err)
+	assert.Equal(t, "Deployed", text)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_UpdateError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("UpdateMessageContext", a.context, "C1H9RESGL", "1360782400.498405",
+		mock.AnythingOfType("slack.MsgOption"),
+	).Return("", "", "", errors.New("message_not_found"))
+
+	err := a.Update("C1H9RESGL", "1360782400.498405", "Deployed")
+	assert.EqualError(t, err, "failed to update message: message_not_found")
+}
+
+func TestAdapter_Delete(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("DeleteMessageContext", a.context, "C1H9RESGL", "1360782400.498405").
+		Return("C1H9RESGL", "1360782400.498405", nil)
+
+	err := a.Delete("C1H9RESGL", "1360782400.498405")
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+
+	slackAPI.On("DeleteMessageContext", a.context, "C1H9RESGL", "1360782500.498405").
+		Return("", "", errors.New("cant_delete_message"))
+
+	err = a.Delete("C1H9RESGL", "1360782500.498405")
+	assert.EqualError(t, err, "failed to delete message: cant_delete_message")
+}
+
+func TestAdapter_SendEphemeral(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	var values url.Values
+	slackAPI.On("PostEphemeralContext", a.context, "C1H9RESGL", "U1234",
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionText
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionPostMessageParameters
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionUser
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionUsername
+	).Run(func(args mock.Arguments) {
+		captureMsgValues(t, &values)(args[1:])
+	}).Return("1360782400.498405", nil)
+
+	err := a.SendEphemeral("Only for you", "C1H9RESGL", "U1234")
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+	assert.Equal(t, "Only for you", values.Get("text"))
+}
+
+func TestAdapter_RespondEphemeral(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	var values url.Values
+	slackAPI.On("PostEphemeralContext", a.context, "C1H9RESGL", "U1234",
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionText
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionTS
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionPostMessageParameters
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionUser
+		mock.AnythingOfType("slack.MsgOption"), // slack.MsgOptionUsername
+	).Run(func(args mock.Arguments) {
+		captureMsgValues(t, &values)(args[1:])
+	}).Return("1360782500.498405", nil)
+
+	msg := joe.Message{
+		Text:     "help",
+		Channel:  "C1H9RESGL/1360782400.498405",
+		ID:       "1360782400.498405",
+		AuthorID: "U1234",
+	}
+
+	err := a.RespondEphemeral(msg, "Usage: deploy <service>")
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+	assert.Equal(t, "Usage: deploy <service>", values.Get("text"))
+	assert.Equal(t, "1360782400.498405", values.Get("thread_ts"))
+}
+
+func TestAdapter_SendEphemeralError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("PostEphemeralContext", a.context, "C1H9RESGL", "U1234",
+		mock.AnythingOfType("slack.MsgOption"),
+		mock.AnythingOfType("slack.MsgOption"),
+		mock.AnythingOfType("slack.MsgOption"),
+		mock.AnythingOfType("slack.MsgOption"),
+	).Return("", errors.New("user_not_in_channel"))
+
+	err := a.SendEphemeral("Only for you", "C1H9RESGL", "U1234")
+	assert.EqualError(t, err, "failed to send ephemeral message: user_not_in_channel")
+}
+
+func TestAdapter_ThreadedResponses(t *testing.T) {
+	brain := joetest.NewBrain(t)
+	a, slackAPI := newTestAdapter(t)
+	expectConversation(slackAPI, a.context, "D023BB3L2", true)
+	a.threadedResponses = true
+
+	done := make(chan bool)
+	go func() {
+		a.handleSlackEvents(brain.Brain)
+		done <- true
+	}()
+
+	topLevel := &slack.MessageEvent{
+		Msg: slack.Msg{
+			Text:      "Hello world",
+			Timestamp: "1360782400.498405",
+			Channel:   "D023BB3L2",
+		},
+	}
+
+	inThread := &slack.MessageEvent{
+		Msg: slack.Msg{
+			Text:            "Hello again",
+			Timestamp:       "1360782500.498405",
+			ThreadTimestamp: "1360782400.498405",
+			Channel:         "D023BB3L2",
+		},
+	}
+
+	a.events <- slackEvent{Data: topLevel}
+	a.events <- slackEvent{Data: inThread}
+
+	close(a.events)
+	<-done
+	brain.Finish()
+
+	events := brain.RecordedEvents()
+	require.Len(t, events, 2)
+	assert.Equal(t, "D023BB3L2/1360782400.498405", events[0].(joe.ReceiveMessageEvent).Channel)
+	assert.Equal(t, "D023BB3L2/1360782400.498405", events[1].(joe.ReceiveMessageEvent).Channel)
+}
+
+func TestAdapter_SendInThread(t *testing.T) {
+	cases := map[string]struct {
+		send      func(a *BotAdapter) error
+		broadcast bool
+	}{
+		"Send to thread channel": {
+			send: func(a *BotAdapter) error {
+				return a.Send("Hello World", "C1H9RESGL/1360782400.498405")
+			},
+		},
+		"SendInThread": {
+			send: func(a *BotAdapter) error {
+				return a.SendInThread("Hello World", "C1H9RESGL", "1360782400.498405")
+			},
+		},
+		"ReplyInThread": {
+			send: func(a *BotAdapter) error {
+				msg := joe.Message{Channel: "C1H9RESGL", ID: "1360782400.498405"}
+				return a.ReplyInThread(msg, "Hello World")
+			},
+		},
+		"ReplyInThread to reply": {
+			send: func(a *BotAdapter) error {
+				msg := joe.Message{Channel: "C1H9RESGL", ID: "1360782500.498405", Data: &slack.MessageEvent{
+					Msg: slack.Msg{ThreadTimestamp: "1360782400.498405"},
+				}}
+				return a.ReplyInThread(msg, "Hello World")
+			},
+		},
+		"SendInThread with broadcast": {
+			send: func(a *BotAdapter) error {
+				a.replyBroadcast = true
+				return a.SendInThread("Hello World", "C1H9RESGL", "1360782400.498405")
+			},
+			broadcast: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, slackAPI := newTestAdapter(t)
+
+			numOpts := 5
+			if c.broadcast {
+				numOpts++
+			}
+
+			var values url.Values
+			expectPostMessage(slackAPI, a.context, "C1H9RESGL", numOpts).
+				Run(captureMsgValues(t, &values)).
+				Return("", "1360782600.498405", nil)
+
+			err := c.send(a)
+			require.NoError(t, err)
+			slackAPI.AssertExpectations(t)
+
+			assert.Equal(t, "Hello World", values.Get("text"))
+			assert.Equal(t, "1360782400.498405", values.Get("thread_ts"))
+			if c.broadcast {
+				assert.Equal(t, "true", values.Get("reply_broadcast"))
+			} else {
+				assert.Empty(t, values.Get("reply_broadcast"))
+			}
+		})
+	}
+}
+
 func TestAdapter_Close(t *testing.T) {
 	a, slackAPI := newTestAdapter(t)
 	slackAPI.On("Disconnect").Return(nil)
@@ -241,7 +473,7 @@ func TestAdapter_UserTypingEvent(t *testing.T) {
 		done <- true
 	}()
 
-	slackAPI.On("GetUserInfo", "UG96B2SGJ").Return(&slack.User{
+	slackAPI.On("GetUserInfoContext", a.context, "UG96B2SGJ").Return(&slack.User{
 		ID:       "UG96B2SGJ",
 		Name:     "JD",
 		RealName: "John Doe",
@@ -274,7 +506,7 @@ func TestAdapter_UserTypingCache(t *testing.T) {
 		done <- true
 	}()
 
-	slackAPI.On("GetUserInfo", "UG96B2SGJ").Return(&slack.User{
+	slackAPI.On("GetUserInfoContext", a.context, "UG96B2SGJ").Return(&slack.User{
 		ID:       "UG96B2SGJ",
 		Name:     "JD",
 		RealName: "John Doe",
@@ -295,6 +527,7 @@ func TestAdapter_UserTypingCache(t *testing.T) {
 
 	events := brain.RecordedEvents()
 	require.NotEmpty(t, events)
+	assert.Equal(t, UserCacheStats{Hits: 2, Misses: 1}, a.UserCacheStats())
 
 	expectedUser := joe.User{ID: "UG96B2SGJ", Name: "JD", RealName: "John Doe"}
 	expectedEvt := joe.UserTypingEvent{User: expectedUser, Channel: "C1H9RESGL"}
@@ -311,7 +544,7 @@ func TestAdapter_UserTypingEventError(t *testing.T) {
 		done <- true
 	}()
 
-	slackAPI.On("GetUserInfo", "UG96B2SGJ").Return(nil, errors.New("something went wrong"))
+	slackAPI.On("GetUserInfoContext", a.context, "UG96B2SGJ").Return(nil, errors.New("something went wrong"))
 
 	a.events <- slackEvent{Data: &slack.UserTypingEvent{
 		User:    "UG96B2SGJ",
@@ -481,6 +714,52 @@ func TestAdapter_React(t *testing.T) {
 	slackAPI.AssertExpectations(t)
 }
 
+func TestAdapter_ReactionRemovedEvent(t *testing.T) {
+	a, _ := newTestAdapter(t)
+
+	evt := &slack.ReactionRemovedEvent{
+		Type:           "reaction_removed",
+		User:           "U1234",
+		ItemUser:       "U0G9QF9C6",
+		Reaction:       "thumbsup",
+		EventTimestamp: "1360782804.083113",
+	}
+
+	evt.Item.Type = "message"
+	evt.Item.Channel = "C0G9QF9GZ"
+	evt.Item.Timestamp = "1360782400.498405"
+
+	own := *evt
+	own.User = a.userID
+
+	events := processTestEvents(t, a, evt, &own)
+	require.Len(t, events, 1)
+
+	expected := ReactionRemovedEvent{
+		Channel:   "C0G9QF9GZ",
+		MessageID: "1360782400.498405",
+		AuthorID:  "U1234",
+		Reaction:  reactions.Reaction{Shortcode: "thumbsup"},
+	}
+	assert.Equal(t, expected, events[0])
+}
+
+func TestAdapter_Unreact(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	msg := joe.Message{
+		Channel: "C0G9QF9GZ/1360782300.498405",
+		ID:      "1360782400.498405",
+	}
+
+	ref := slack.NewRefToMessage("C0G9QF9GZ", msg.ID)
+	slackAPI.On("RemoveReactionContext", a.context, "thumbsup", ref).Return(nil)
+
+	err := a.Unreact(reactions.Thumbsup, msg)
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+}
+
 func TestAdapter_IgnoreUnknownEventTypes(t *testing.T) {
 	brain := joetest.NewBrain(t)
 	a, _ := newTestAdapter(t)
@@ -542,6 +821,17 @@ func TestAdapter_LogUnknownEventTypes(t *testing.T) {
 	assert.Equal(t, evt.Data, fields["data"])
 }
 
+func TestNewAdapter_LogUnknownMessageTypes(t *testing.T) {
+	ctx := context.Background()
+	client := new(mockSlack)
+	client.On("AuthTestContext", ctx).Return(&slack.AuthTestResponse{UserID: "42"}, nil)
+
+	conf := Config{Logger: zaptest.NewLogger(t), LogUnknownMessageTypes: true}
+	a, err := newAdapter(ctx, client, nil, make(chan slackEvent), conf)
+	require.NoError(t, err)
+	assert.True(t, a.logUnknownMessageTypes)
+}
+
 func TestAdapter_RTMError(t *testing.T) {
 	brain := joetest.NewBrain(t)
 	a, _ := newTestAdapter(t)
@@ -608,6 +898,41 @@ func TestAdapter_UnmarshallingErrorEvent(t *testing.T) {
 	assert.Equal(t, "failure", fields["error"])
 }
 
+// expectPostMessage registers an expected call to PostMessageContext with the
+// given number of message options.
+func expectPostMessage(m *mockSlack, ctx context.Context, channelID string, numOpts int) *mock.Call {
+	args := []interface{}{ctx, channelID}
+	for i := 0; i < numOpts; i++ {
+		args = append(args, mock.AnythingOfType("slack.MsgOption"))
+	}
+
+	return m.On("PostMessageContext", args...)
+}
+
+// expectConversation registers an expected lookup of the given conversation,
+// which the adapter needs to tell if a message is a direct message.
+func expectConversation(m *mockSlack, ctx context.Context, channelID string, isIM bool) *mock.Call {
+	channel := &slack.Channel{}
+	channel.ID = channelID
+	channel.IsIM = isIM
+	return m.On("GetConversationInfoContext", ctx, channelID, false).Return(channel, nil)
+}
+
+// captureMsgValues returns a function that can be passed to mock.Call.Run to
+// capture the request parameters that the slack.MsgOption arguments produce.
+func captureMsgValues(t *testing.T, values *url.Values) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		var opts []slack.MsgOption
+		for _, arg := range args[2:] {
+			opts = append(opts, arg.(slack.MsgOption))
+		}
+
+		_, v, err := slack.UnsafeApplyMsgOptions("", "", "", opts...)
+		require.NoError(t, err)
+		*values = v
+	}
+}
+
 type mockSlack struct {
 	mock.Mock
 }
@@ -632,13 +957,43 @@ func (m *mockSlack) PostMessageContext(ctx context.Context, channelID string,
 	return args.String(0), args.String(1), args.Error(2)
 }
 
+func (m *mockSlack) UpdateMessageContext(ctx context.Context, channelID, timestamp string,
+	opts ...slack.MsgOption) (respChannel, respTimestamp, text string, err error) {
+	callArgs := []interface{}{ctx, channelID, timestamp}
+	for _, o := range opts {
+		callArgs = append(callArgs, o)
+	}
+	args := m.Called(callArgs...)
+	return args.String(0), args.String(1), args.String(2), args.Error(3)
+}
+
+func (m *mockSlack) DeleteMessageContext(ctx context.Context, channelID, timestamp string) (respChannel, respTimestamp string, err error) {
+	args := m.Called(ctx, channelID, timestamp)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *mockSlack) PostEphemeralContext(ctx context.Context, channelID, userID string,
+	opts ...slack.MsgOption) (timestamp string, err error) {
+	callArgs := []interface{}{ctx, channelID, userID}
+	for _, o := range opts {
+		callArgs = append(callArgs, o)
+	}
+	args := m.Called(callArgs...)
+	return args.String(0), args.Error(1)
+}
+
 func (m *mockSlack) AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
 	args := m.Called(ctx, name, item)
 	return args.Error(0)
 }
 
-func (m *mockSlack) GetUserInfo(user string) (usr *slack.User, err error) {
-	args := m.Called(user)
+func (m *mockSlack) RemoveReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	args := m.Called(ctx, name, item)
+	return args.Error(0)
+}
+
+func (m *mockSlack) GetUserInfoContext(ctx context.Context, user string) (usr *slack.User, err error) {
+	args := m.Called(ctx, user)
 	if x := args.Get(0); x != nil {
 		usr = x.(*slack.User)
 	}
@@ -646,6 +1001,74 @@ func (m *mockSlack) GetUserInfo(user string) (usr *slack.User, err error) {
 	return usr, args.Error(1)
 }
 
+func (m *mockSlack) GetUsersContext(ctx context.Context) (users []slack.User, err error) {
+	args := m.Called(ctx)
+	if x := args.Get(0); x != nil {
+		users = x.([]slack.User)
+	}
+
+	return users, args.Error(1)
+}
+
+func (m *mockSlack) GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	args := m.Called(ctx, channelID, includeLocale)
+	if x := args.Get(0); x != nil {
+		channel = x.(*slack.Channel)
+	}
+
+	return channel, args.Error(1)
+}
+
+func (m *mockSlack) GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) (channels []slack.Channel, nextCursor string, err error) {
+	args := m.Called(ctx, params)
+	if x := args.Get(0); x != nil {
+		channels = x.([]slack.Channel)
+	}
+
+	return channels, args.String(1), args.Error(2)
+}
+
+func (m *mockSlack) OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (channel *slack.Channel, noOp, alreadyOpen bool, err error) {
+	args := m.Called(ctx, params)
+	if x := args.Get(0); x != nil {
+		channel = x.(*slack.Channel)
+	}
+
+	return channel, args.Bool(1), args.Bool(2), args.Error(3)
+}
+
+func (m *mockSlack) UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (file *slack.File, err error) {
+	args := m.Called(ctx, params)
+	if x := args.Get(0); x != nil {
+		file = x.(*slack.File)
+	}
+
+	return file, args.Error(1)
+}
+
+func (m *mockSlack) GetFileInfoContext(ctx context.Context, fileID string, count, page int) (file *slack.File, comments []slack.Comment, paging *slack.Paging, err error) {
+	args := m.Called(ctx, fileID, count, page)
+	if x := args.Get(0); x != nil {
+		file = x.(*slack.File)
+	}
+
+	return file, nil, nil, args.Error(1)
+}
+
+func (m *mockSlack) GetFile(downloadURL string, writer io.Writer) error {
+	args := m.Called(downloadURL, writer)
+	return args.Error(0)
+}
+
+func (m *mockSlack) GetBotInfoContext(ctx context.Context, bot string) (b *slack.Bot, err error) {
+	args := m.Called(ctx, bot)
+	if x := args.Get(0); x != nil {
+		b = x.(*slack.Bot)
+	}
+
+	return b, args.Error(1)
+}
+
 func (m *mockSlack) Disconnect() error {
 	args := m.Called()
 	return args.Error(0)
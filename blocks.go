@@ -0,0 +1,222 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// Limits of the Slack Block Kit that are validated before a message is sent.
+// See https://api.slack.com/reference/block-kit/blocks
+const (
+	maxBlocks          = 50
+	maxSectionText     = 3000
+	maxSectionFields   = 10
+	maxFieldText       = 2000
+	maxContextElements = 10
+	maxActionElements  = 25
+	maxButtonText      = 75
+)
+
+// A BlockBuilder can be used to create Slack messages with common Block Kit
+// layouts. Each method appends a new block to the message and returns the
+// builder so calls can be chained. The blocks can then be sent via the
+// BotAdapter.SendBlocks(…) function:
+//
+//	blocks := slack.NewBlockBuilder().
+//	    Section("*Deployment finished*").
+//	    Fields("*Service*\nbilling", "*Version*\nv1.2.3").
+//	    Divider().
+//	    Buttons(slack.Button("rollback", "v1.2.2", "Rollback")).
+//	    Blocks()
+//
+//	err := adapter.SendBlocks(channelID, blocks...)
+//
+// See https://api.slack.com/block-kit
+type BlockBuilder struct {
+	blocks []slack.Block
+}
+
+// NewBlockBuilder returns a new empty BlockBuilder.
+func NewBlockBuilder() *BlockBuilder {
+	return new(BlockBuilder)
+}
+
+// Section appends a section block with the given markdown text.
+func (b *BlockBuilder) Section(text string) *BlockBuilder {
+	txt := slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+	return b.Add(slack.NewSectionBlock(txt, nil, nil))
+}
+
+// Fields appends a section block that displays the given markdown texts in a
+// compact two column layout.
+func (b *BlockBuilder) Fields(fields ...string) *BlockBuilder {
+	objects := make([]*slack.TextBlockObject, len(fields))
+	for i, f := range fields {
+		objects[i] = slack.NewTextBlockObject(slack.MarkdownType, f, false, false)
+	}
+
+	return b.Add(slack.NewSectionBlock(nil, objects, nil))
+}
+
+// Divider appends a divider block that visually separates blocks.
+func (b *BlockBuilder) Divider() *BlockBuilder {
+	return b.Add(slack.NewDividerBlock())
+}
+
+// Context appends a context block that displays the given markdown texts in a
+// small font (e.g. for additional information like timestamps or authors).
+func (b *BlockBuilder) Context(texts ...string) *BlockBuilder {
+	elements := make([]slack.MixedElement, len(texts))
+	for i, txt := range texts {
+		elements[i] = slack.NewTextBlockObject(slack.MarkdownType, txt, false, false)
+	}
+
+	return b.Add(slack.NewContextBlock("", elements...))
+}
+
+// Buttons appends an actions block with the given buttons.
+// See the Button(…) function to create new buttons.
+func (b *BlockBuilder) Buttons(buttons ...*slack.ButtonBlockElement) *BlockBuilder {
+	elements := make([]slack.BlockElement, len(buttons))
+	for i, btn := range buttons {
+		elements[i] = btn
+	}
+
+	return b.Add(slack.NewActionBlock("", elements...))
+}
+
+// Add appends the given blocks as they are. This can be used to add blocks
+// that are not directly supported by the BlockBuilder.
+func (b *BlockBuilder) Add(blocks ...slack.Block) *BlockBuilder {
+	b.blocks = append(b.blocks, blocks...)
+	return b
+}
+
+// Blocks returns all blocks that have been added to the builder.
+func (b *BlockBuilder) Blocks() []slack.Block {
+	return b.blocks
+}
+
+// Button returns a new button with the given label. When the button is
+// clicked, Slack sends a block action with the action ID and value.
+func Button(actionID, value, label string) *slack.ButtonBlockElement {
+	txt := slack.NewTextBlockObject(slack.PlainTextType, label, false, false)
+	return slack.NewButtonBlockElement(actionID, value, txt)
+}
+
+// SendBlocks sends a message that consists of the given Block Kit blocks to a
+// channel. The blocks are validated against the limits of Slack before they
+// are sent. The plain text that is displayed in notifications is derived from
+// the texts of the blocks.
+func (a *BotAdapter) SendBlocks(channelID string, blocks ...slack.Block) error {
+	err := validateBlocks(blocks)
+	if err != nil {
+		return err
+	}
+
+	channelID, threadTS := splitThreadChannel(channelID)
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(blocksFallbackText(blocks), false),
+		slack.MsgOptionBlocks(blocks...),
+	}
+
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+
+	_, err = a.send(channelID, opts...)
+	return err
+}
+
+// validateBlocks checks the given blocks against the documented limits of Slack.
+func validateBlocks(blocks []slack.Block) error {
+	if len(blocks) == 0 {
+		return errors.New("message must contain at least one block")
+	}
+
+	if len(blocks) > maxBlocks {
+		return fmt.Errorf("message has %d blocks but Slack allows at most %d", len(blocks), maxBlocks)
+	}
+
+	for i, block := range blocks {
+		err := validateBlock(block)
+		if err != nil {
+			return fmt.Errorf("invalid block %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func validateBlock(block slack.Block) error {
+	switch b := block.(type) {
+	case *slack.SectionBlock:
+		if b.Text == nil && len(b.Fields) == 0 {
+			return errors.New("section must have a text or fields")
+		}
+		if b.Text != nil && textLen(b.Text) > maxSectionText {
+			return fmt.Errorf("section text has %d characters but Slack allows at most %d", textLen(b.Text), maxSectionText)
+		}
+		if len(b.Fields) > maxSectionFields {
+			return fmt.Errorf("section has %d fields but Slack allows at most %d", len(b.Fields), maxSectionFields)
+		}
+		for _, f := range b.Fields {
+			if textLen(f) > maxFieldText {
+				return fmt.Errorf("section field has %d characters but Slack allows at most %d", textLen(f), maxFieldText)
+			}
+		}
+
+	case *slack.ContextBlock:
+		if n := len(b.ContextElements.Elements); n > maxContextElements {
+			return fmt.Errorf("context has %d elements but Slack allows at most %d", n, maxContextElements)
+		}
+
+	case *slack.ActionBlock:
+		if n := len(b.Elements.ElementSet); n > maxActionElements {
+			return fmt.Errorf("actions block has %d elements but Slack allows at most %d", n, maxActionElements)
+		}
+		for _, e := range b.Elements.ElementSet {
+			btn, ok := e.(*slack.ButtonBlockElement)
+			if ok && btn.Text != nil && textLen(btn.Text) > maxButtonText {
+				return fmt.Errorf("button text has %d characters but Slack allows at most %d", textLen(btn.Text), maxButtonText)
+			}
+		}
+	}
+
+	return nil
+}
+
+// textLen returns the number of characters (not bytes) of the given text.
+func textLen(txt *slack.TextBlockObject) int {
+	return utf8.RuneCountInString(txt.Text)
+}
+
+// blocksFallbackText returns the plain text of all blocks which is displayed
+// in notifications and clients that cannot render blocks.
+func blocksFallbackText(blocks []slack.Block) string {
+	var lines []string
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *slack.SectionBlock:
+			if b.Text != nil {
+				lines = append(lines, b.Text.Text)
+			}
+			for _, f := range b.Fields {
+				lines = append(lines, f.Text)
+			}
+
+		case *slack.ContextBlock:
+			for _, e := range b.ContextElements.Elements {
+				if txt, ok := e.(*slack.TextBlockObject); ok {
+					lines = append(lines, txt.Text)
+				}
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,117 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockBuilder(t *testing.T) {
+	blocks := NewBlockBuilder().
+		Section("*Deployment finished*").
+		Fields("*Service*\nbilling", "*Version*\nv1.2.3").
+		Divider().
+		Context("Deployed by <@U1234>").
+		Buttons(Button("rollback", "v1.2.2", "Rollback")).
+		Blocks()
+
+	require.Len(t, blocks, 5)
+	assert.IsType(t, new(slack.SectionBlock), blocks[0])
+	assert.IsType(t, new(slack.SectionBlock), blocks[1])
+	assert.IsType(t, new(slack.DividerBlock), blocks[2])
+	assert.IsType(t, new(slack.ContextBlock), blocks[3])
+	assert.IsType(t, new(slack.ActionBlock), blocks[4])
+
+	assert.Len(t, blocks[1].(*slack.SectionBlock).Fields, 2)
+
+	btn := blocks[4].(*slack.ActionBlock).Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	assert.Equal(t, "rollback", btn.ActionID)
+	assert.Equal(t, "v1.2.2", btn.Value)
+	assert.Equal(t, "Rollback", btn.Text.Text)
+
+	assert.NoError(t, validateBlocks(blocks))
+	assert.Equal(t, "*Deployment finished*\n*Service*\nbilling\n*Version*\nv1.2.3\nDeployed by <@U1234>", blocksFallbackText(blocks))
+}
+
+func TestValidateBlocks(t *testing.T) {
+	tooManyBlocks := NewBlockBuilder()
+	for i := 0; i < 51; i++ {
+		tooManyBlocks.Divider()
+	}
+
+	tooManyFields := make([]string, 11)
+	for i := range tooManyFields {
+		tooManyFields[i] = "field"
+	}
+
+	cases := map[string]struct {
+		blocks []slack.Block
+		err    string
+	}{
+		"no blocks": {
+			blocks: nil,
+			err:    "message must contain at least one block",
+		},
+		"too many blocks": {
+			blocks: tooManyBlocks.Blocks(),
+			err:    "message has 51 blocks but Slack allows at most 50",
+		},
+		"section text too long": {
+			blocks: NewBlockBuilder().Divider().Section(strings.Repeat("x", 3001)).Blocks(),
+			err:    "invalid block 1: section text has 3001 characters but Slack allows at most 3000",
+		},
+		"empty section": {
+			blocks: NewBlockBuilder().Fields().Blocks(),
+			err:    "invalid block 0: section must have a text or fields",
+		},
+		"too many fields": {
+			blocks: NewBlockBuilder().Fields(tooManyFields...).Blocks(),
+			err:    "invalid block 0: section has 11 fields but Slack allows at most 10",
+		},
+		"button text too long": {
+			blocks: NewBlockBuilder().Buttons(Button("a", "b", strings.Repeat("ä", 76))).Blocks(),
+			err:    "invalid block 0: button text has 76 characters but Slack allows at most 75",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualError(t, validateBlocks(c.blocks), c.err)
+		})
+	}
+}
+
+func TestAdapter_SendBlocks(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	var values url.Values
+	expectPostMessage(slackAPI, a.context, "C1H9RESGL", 5).
+		Run(captureMsgValues(t, &values)).
+		Return("", "", nil)
+
+	blocks := NewBlockBuilder().Section("Hello *World*").Divider().Blocks()
+	err := a.SendBlocks("C1H9RESGL", blocks...)
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+
+	assert.Equal(t, "Hello *World*", values.Get("text"))
+
+	var sentBlocks []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(values.Get("blocks")), &sentBlocks))
+	require.Len(t, sentBlocks, 2)
+	assert.Equal(t, "section", sentBlocks[0]["type"])
+	assert.Equal(t, "divider", sentBlocks[1]["type"])
+}
+
+func TestAdapter_SendBlocks_Invalid(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	err := a.SendBlocks("C1H9RESGL")
+	assert.EqualError(t, err, "message must contain at least one block")
+	slackAPI.AssertNotCalled(t, "PostMessageContext")
+}
@@ -0,0 +1,202 @@
+package slack
+
+import (
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// defaultBotLoopWindow is the time window in which the number of replies to
+// another bot is limited if no other window is configured.
+const defaultBotLoopWindow = time.Minute
+
+// botMessageFilter decides which messages of other bots and integrations are
+// passed to the brain and limits how often the bot replies to another bot so
+// two bots cannot keep each other busy forever.
+type botMessageFilter struct {
+	ignore     bool
+	allowed    map[string]bool // bot IDs and app IDs
+	maxReplies int             // zero if loop detection is disabled
+	window     time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	appIDs  map[string]string        // maps bot IDs to the IDs of their apps
+	replies map[string]*botLoopCount // keyed by channel and bot
+}
+
+type botLoopCount struct {
+	windowStart time.Time
+	count       int
+}
+
+// newBotMessageFilter returns the filter of the given configuration or nil
+// if all bot messages are passed to the brain.
+func newBotMessageFilter(conf BotMessageConfig) *botMessageFilter {
+	if !conf.Ignore && conf.MaxReplies == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(conf.AllowedBots))
+	for _, id := range conf.AllowedBots {
+		allowed[id] = true
+	}
+
+	window := conf.Window
+	if window == 0 {
+		window = defaultBotLoopWindow
+	}
+
+	return &botMessageFilter{
+		ignore:     conf.Ignore,
+		allowed:    allowed,
+		maxReplies: conf.MaxReplies,
+		window:     window,
+		now:        time.Now,
+		appIDs:     map[string]string{},
+		replies:    map[string]*botLoopCount{},
+	}
+}
+
+// isBotMessage returns true if the message was sent by a bot or integration.
+func isBotMessage(ev *slack.MessageEvent) bool {
+	return ev.SubType == "bot_message" || ev.BotID != ""
+}
+
+// allowBotMessage returns false if the message of another bot must be ignored
+// because the adapter is configured to ignore bot messages.
+func (a *BotAdapter) allowBotMessage(ev *slack.MessageEvent) bool {
+	f := a.botMessages
+	if f == nil || !f.ignore || !isBotMessage(ev) {
+		return true
+	}
+
+	if a.isAllowedBot(ev.BotID) {
+		return true
+	}
+
+	a.logger.Debug("Ignoring event",
+		zap.String("reason", "message is from a bot"),
+		zap.String("channel_id", ev.Channel),
+		zap.String("bot_id", ev.BotID),
+	)
+	return false
+}
+
+// isAllowedBot returns true if the bot or its app are allowed explicitly.
+func (a *BotAdapter) isAllowedBot(botID string) bool {
+	f := a.botMessages
+	if botID == "" || len(f.allowed) == 0 {
+		return false
+	}
+
+	if f.allowed[botID] {
+		return true
+	}
+
+	appID, err := a.botAppID(botID)
+	if err != nil {
+		a.logger.Error("Failed to get app of bot",
+			zap.String("bot_id", botID),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	return appID != "" && f.allowed[appID]
+}
+
+// botAppID returns the ID of the app of the given bot. The result is cached
+// since the app of a bot never changes.
+func (a *BotAdapter) botAppID(botID string) (string, error) {
+	f := a.botMessages
+
+	f.mu.Lock()
+	appID, ok := f.appIDs[botID]
+	f.mu.Unlock()
+	if ok {
+		return appID, nil
+	}
+
+	bot, err := a.slack.GetBotInfoContext(a.context, botID)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	f.appIDs[botID] = bot.AppID
+	f.mu.Unlock()
+
+	return bot.AppID, nil
+}
+
+// allowBotReply returns false if the bot already replied too often to the
+// other bot that sent the message within the configured time window.
+func (a *BotAdapter) allowBotReply(ev *slack.MessageEvent) bool {
+	f := a.botMessages
+	if f == nil || f.maxReplies == 0 || !isBotMessage(ev) {
+		return true
+	}
+
+	sender := ev.BotID
+	if sender == "" {
+		sender = ev.User
+	}
+
+	count := f.countReply(ev.Channel + "/" + sender)
+	if count <= f.maxReplies {
+		return true
+	}
+
+	if count == f.maxReplies+1 {
+		a.logger.Warn("Detected possible loop with another bot, ignoring its messages",
+			zap.String("channel_id", ev.Channel),
+			zap.String("bot_id", ev.BotID),
+			zap.String("user_id", ev.User),
+			zap.Int("max_replies", f.maxReplies),
+			zap.Duration("window", f.window),
+		)
+	}
+
+	a.logger.Debug("Ignoring event",
+		zap.String("reason", "too many replies to bot"),
+		zap.String("channel_id", ev.Channel),
+		zap.String("bot_id", ev.BotID),
+	)
+	return false
+}
+
+// countReply counts a reply to the bot with the given key and returns the
+// number of replies in the current time window.
+func (f *botMessageFilter) countReply(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	c, ok := f.replies[key]
+	if !ok {
+		f.pruneLocked(now)
+		c = new(botLoopCount)
+		f.replies[key] = c
+	}
+
+	if now.Sub(c.windowStart) >= f.window {
+		c.windowStart = now
+		c.count = 0
+	}
+
+	c.count++
+	return c.count
+}
+
+// pruneLocked removes the counters whose time window has ended so the
+// counters of bots that are not active anymore do not accumulate.
+func (f *botMessageFilter) pruneLocked(now time.Time) {
+	for key, c := range f.replies {
+		if now.Sub(c.windowStart) >= f.window {
+			delete(f.replies, key)
+		}
+	}
+}
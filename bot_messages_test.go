@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBotTestMessage(botID, text string) *slack.MessageEvent {
+	return &slack.MessageEvent{Msg: slack.Msg{
+		Channel:   "C1",
+		SubType:   "bot_message",
+		BotID:     botID,
+		Text:      text,
+		Timestamp: "1360782400.498405",
+	}}
+}
+
+func TestAdapter_IgnoreBotMessages(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.botMessages = newBotMessageFilter(BotMessageConfig{
+		Ignore:      true,
+		AllowedBots: []string{"B1", "A2"},
+	})
+
+	slackAPI.On("GetBotInfoContext", a.context, "B2").Return(&slack.Bot{ID: "B2", AppID: "A2"}, nil).Once()
+	slackAPI.On("GetBotInfoContext", a.context, "B3").Return(&slack.Bot{ID: "B3", AppID: "A3"}, nil).Once()
+	slackAPI.On("GetBotInfoContext", a.context, "B4").Return(nil, errors.New("bot_not_found")).Once()
+
+	// Integrations without a bot user can also set the bot ID on normal messages.
+	integration := &slack.MessageEvent{Msg: slack.Msg{
+		Channel:   "C1",
+		User:      "U2",
+		BotID:     "B3",
+		Text:      "<@42> from an integration",
+		Timestamp: "1360782400.498405",
+	}}
+
+	events := processTestEvents(t, a,
+		newBotTestMessage("B1", "<@42> allowed by bot ID"),
+		newBotTestMessage("B2", "<@42> allowed by app ID"),
+		newBotTestMessage("B2", "<@42> app ID is cached"),
+		newBotTestMessage("B3", "<@42> not allowed"),
+		newBotTestMessage("B4", "<@42> unknown bot"),
+		integration,
+		&slack.MessageEvent{
+			Msg:        slack.Msg{Channel: "C1", SubType: "message_changed"},
+			SubMessage: &slack.Msg{SubType: "bot_message", BotID: "B3", Text: "edited"},
+		},
+		&slack.MessageEvent{Msg: slack.Msg{
+			Channel:   "C1",
+			User:      "U1",
+			Text:      "<@42> from a user",
+			Timestamp: "1360782400.498405",
+		}},
+	)
+
+	require.Len(t, events, 4)
+	assert.Equal(t, "allowed by bot ID", events[0].(joe.ReceiveMessageEvent).Text)
+	assert.Equal(t, "allowed by app ID", events[1].(joe.ReceiveMessageEvent).Text)
+	assert.Equal(t, "app ID is cached", events[2].(joe.ReceiveMessageEvent).Text)
+	assert.Equal(t, "from a user", events[3].(joe.ReceiveMessageEvent).Text)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_BotLoopDetection(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	a.listenPassive = true
+	a.botMessages = newBotMessageFilter(BotMessageConfig{MaxReplies: 2, Window: time.Minute})
+
+	now := time.Now()
+	a.botMessages.now = func() time.Time { return now }
+
+	userMsg := &slack.MessageEvent{Msg: slack.Msg{
+		Channel:   "C1",
+		User:      "U1",
+		Text:      "user",
+		Timestamp: "1360782400.498405",
+	}}
+
+	otherChannel := newBotTestMessage("B1", "other channel")
+	otherChannel.Channel = "C2"
+
+	events := processTestEvents(t, a,
+		newBotTestMessage("B1", "1"),
+		newBotTestMessage("B1", "2"),
+		newBotTestMessage("B1", "3"), // loop
+		newBotTestMessage("B2", "other bot"),
+		otherChannel,
+		userMsg,
+		userMsg,
+		userMsg,
+	)
+
+	var texts []string
+	for _, evt := range events {
+		texts = append(texts, evt.(joe.ReceiveMessageEvent).Text)
+	}
+	assert.Equal(t, []string{"1", "2", "other bot", "other channel", "user", "user", "user"}, texts)
+
+	// The bot receives messages again in the next time window.
+	now = now.Add(time.Minute)
+	assert.True(t, a.allowBotReply(newBotTestMessage("B1", "4")))
+
+	// Expired counters of other bots are removed when new bots show up.
+	assert.True(t, a.allowBotReply(newBotTestMessage("B3", "new bot")))
+	assert.Len(t, a.botMessages.replies, 2)
+	assert.Contains(t, a.botMessages.replies, "C1/B1")
+	assert.Contains(t, a.botMessages.replies, "C1/B3")
+}
+
+func TestNewBotMessageFilter(t *testing.T) {
+	assert.Nil(t, newBotMessageFilter(BotMessageConfig{}))
+
+	f := newBotMessageFilter(BotMessageConfig{MaxReplies: 5})
+	require.NotNil(t, f)
+	assert.False(t, f.ignore)
+	assert.Equal(t, defaultBotLoopWindow, f.window)
+}
@@ -0,0 +1,350 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// defaultChannelCacheTTL is the time after which cached channel information
+// is requested again from the Slack API.
+const defaultChannelCacheTTL = 10 * time.Minute
+
+// Errors that are returned if a channel name (e.g. "#deployments") or user
+// name (e.g. "@jd") that was passed instead of a channel ID cannot be used.
+var (
+	ErrUnknownChannel  = errors.New("unknown channel")
+	ErrArchivedChannel = errors.New("channel is archived")
+	ErrUnknownUser     = errors.New("unknown user")
+)
+
+// channelCache stores the channels that the BotAdapter looked up via the
+// Slack API until their TTL expires.
+type channelCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu             sync.Mutex
+	channels       map[string]channelCacheEntry // maps channel IDs to channels
+	names          map[string]string            // maps channel names to IDs
+	directMessages map[string]string            // maps user names to IDs of DM channels
+	userNames      map[string]string            // maps user names to IDs
+	usersListed    time.Time                    // time at which all users were listed the last time
+	types          map[string]typeCacheEntry    // maps channel IDs to their type
+	typesPruned    time.Time                    // time at which expired types were removed the last time
+	listed         time.Time                    // time at which all channels were listed the last time
+}
+
+type channelCacheEntry struct {
+	channel    *slack.Channel
+	expiration time.Time
+}
+
+type typeCacheEntry struct {
+	typ        ConversationType // empty if the type could not be looked up
+	expiration time.Time
+}
+
+func newChannelCache(ttl time.Duration) *channelCache {
+	return &channelCache{
+		ttl:            ttl,
+		now:            time.Now,
+		channels:       map[string]channelCacheEntry{},
+		names:          map[string]string{},
+		directMessages: map[string]string{},
+		userNames:      map[string]string{},
+		types:          map[string]typeCacheEntry{},
+	}
+}
+
+func (c *channelCache) get(channelID string) (*slack.Channel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.getLocked(channelID)
+}
+
+func (c *channelCache) getLocked(channelID string) (*slack.Channel, bool) {
+	entry, ok := c.channels[channelID]
+	if !ok {
+		return nil, false
+	}
+
+	if c.now().After(entry.expiration) {
+		delete(c.channels, channelID)
+		return nil, false
+	}
+
+	return entry.channel, true
+}
+
+func (c *channelCache) getByName(name string) (*slack.Channel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, ok := c.names[name]
+	if !ok {
+		return nil, false
+	}
+
+	channel, ok := c.getLocked(id)
+	if !ok || channel.Name != name {
+		// The channel has expired or it was renamed in the meantime.
+		delete(c.names, name)
+		return nil, false
+	}
+
+	return channel, true
+}
+
+func (c *channelCache) add(channel *slack.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.channels[channel.ID] = channelCacheEntry{
+		channel:    channel,
+		expiration: c.now().Add(c.ttl),
+	}
+
+	if channel.Name != "" {
+		c.names[channel.Name] = channel.ID
+	}
+}
+
+// listingExpired returns true if all channels should be listed again to
+// resolve unknown channel names.
+func (c *channelCache) listingExpired() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now().After(c.listed.Add(c.ttl))
+}
+
+func (c *channelCache) setListed() {
+	c.mu.Lock()
+	c.listed = c.now()
+	c.mu.Unlock()
+}
+
+// userID returns the ID of the user with the given name as of the last time
+// all users were listed.
+func (c *channelCache) userID(username string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, ok := c.userNames[username]
+	return id, ok
+}
+
+// usersListingExpired returns true if all users should be listed again to
+// resolve unknown user names.
+func (c *channelCache) usersListingExpired() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now().After(c.usersListed.Add(c.ttl))
+}
+
+// setUsers replaces the known user names with the names of the given users
+// that were just listed.
+func (c *channelCache) setUsers(userNames map[string]string) {
+	c.mu.Lock()
+	c.userNames = userNames
+	c.usersListed = c.now()
+	c.mu.Unlock()
+}
+
+func (c *channelCache) directMessage(username string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, ok := c.directMessages[username]
+	return id, ok
+}
+
+func (c *channelCache) addDirectMessage(username, channelID string) {
+	c.mu.Lock()
+	c.directMessages[username] = channelID
+	c.mu.Unlock()
+}
+
+func (c *channelCache) getType(channelID string) (ConversationType, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.types[channelID]
+	if !ok {
+		return "", false
+	}
+
+	if c.now().After(entry.expiration) {
+		delete(c.types, channelID)
+		return "", false
+	}
+
+	return entry.typ, true
+}
+
+func (c *channelCache) setType(channelID string, typ ConversationType) {
+	c.mu.Lock()
+	c.setTypeLocked(channelID, typ)
+	c.mu.Unlock()
+}
+
+// setUnknownType remembers that the type of the channel could not be looked
+// up, so it is not requested again until the TTL of the cache expired.
+func (c *channelCache) setUnknownType(channelID string) {
+	c.mu.Lock()
+	c.setTypeLocked(channelID, "")
+	c.mu.Unlock()
+}
+
+// setTypeLocked stores the type of the channel until the TTL of the cache
+// expired. Since types are set for every message of the Events API, expired
+// entries of channels that are not active anymore are removed at most once
+// per TTL so they do not accumulate.
+func (c *channelCache) setTypeLocked(channelID string, typ ConversationType) {
+	now := c.now()
+	if now.After(c.typesPruned.Add(c.ttl)) {
+		for id, entry := range c.types {
+			if now.After(entry.expiration) {
+				delete(c.types, id)
+			}
+		}
+		c.typesPruned = now
+	}
+
+	c.types[channelID] = typeCacheEntry{typ: typ, expiration: now.Add(c.ttl)}
+}
+
+// slackChannel returns the channel with the given ID either from the channel
+// cache or from the Slack API.
+func (a *BotAdapter) slackChannel(channelID string) (*slack.Channel, error) {
+	if channel, ok := a.channels.get(channelID); ok {
+		return channel, nil
+	}
+
+	channel, err := a.slack.GetConversationInfoContext(a.context, channelID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	a.channels.add(channel)
+	return channel, nil
+}
+
+// resolveChannel returns the ID of the given channel which can either be a
+// channel ID, a channel name (e.g. "#deployments") or a user name (e.g. "@jd")
+// in which case the ID of the direct message channel with this user is returned.
+func (a *BotAdapter) resolveChannel(channel string) (string, error) {
+	switch {
+	case strings.HasPrefix(channel, "#"):
+		return a.channelIDByName(strings.TrimPrefix(channel, "#"))
+	case strings.HasPrefix(channel, "@"):
+		return a.directMessageChannelID(strings.TrimPrefix(channel, "@"))
+	default:
+		return channel, nil
+	}
+}
+
+func (a *BotAdapter) channelIDByName(name string) (string, error) {
+	channel, ok := a.channels.getByName(name)
+	if !ok && a.channels.listingExpired() {
+		err := a.loadChannels()
+		if err != nil {
+			return "", err
+		}
+
+		channel, ok = a.channels.getByName(name)
+	}
+
+	if !ok {
+		return "", fmt.Errorf("#%s: %w", name, ErrUnknownChannel)
+	}
+
+	if channel.IsArchived {
+		return "", fmt.Errorf("#%s: %w", name, ErrArchivedChannel)
+	}
+
+	return channel.ID, nil
+}
+
+// loadChannels adds all public and private channels the bot can see to the
+// channel cache.
+func (a *BotAdapter) loadChannels() error {
+	params := &slack.GetConversationsParameters{
+		Limit: 1000,
+		Types: []string{"public_channel", "private_channel"},
+	}
+
+	for {
+		channels, cursor, err := a.slack.GetConversationsContext(a.context, params)
+		if err != nil {
+			return fmt.Errorf("failed to list channels: %w", err)
+		}
+
+		for i := range channels {
+			a.channels.add(&channels[i])
+		}
+
+		if cursor == "" {
+			break
+		}
+
+		params.Cursor = cursor
+	}
+
+	a.channels.setListed()
+	return nil
+}
+
+func (a *BotAdapter) directMessageChannelID(username string) (string, error) {
+	if id, ok := a.channels.directMessage(username); ok {
+		return id, nil
+	}
+
+	userID, err := a.userIDByName(username)
+	if err != nil {
+		return "", err
+	}
+
+	channel, _, _, err := a.slack.OpenConversationContext(a.context, &slack.OpenConversationParameters{
+		Users: []string{userID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open direct message with @%s: %w", username, err)
+	}
+
+	a.channels.addDirectMessage(username, channel.ID)
+	return channel.ID, nil
+}
+
+// userIDByName returns the ID of the user with the given name. All users are
+// listed to find the user, but only if the name is not known already and the
+// users were not listed recently, since this requires many requests in large
+// workspaces.
+func (a *BotAdapter) userIDByName(username string) (string, error) {
+	userID, ok := a.channels.userID(username)
+	if ok {
+		if user, cached := a.users.Get(userID); cached && (user.Name != username || user.Deleted) {
+			// The user was renamed or deleted in the meantime.
+			ok = false
+		}
+	}
+
+	if !ok && a.channels.usersListingExpired() {
+		err := a.loadUsers()
+		if err != nil {
+			return "", err
+		}
+
+		userID, ok = a.channels.userID(username)
+	}
+
+	if !ok {
+		return "", fmt.Errorf("@%s: %w", username, ErrUnknownUser)
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,175 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testChannel(id, name string) slack.Channel {
+	var c slack.Channel
+	c.ID = id
+	c.Name = name
+	return c
+}
+
+func conversationsPage(cursor string) interface{} {
+	return mock.MatchedBy(func(params *slack.GetConversationsParameters) bool {
+		return params.Cursor == cursor
+	})
+}
+
+func TestAdapter_SendToChannelName(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	archived := testChannel("C3", "old-deployments")
+	archived.IsArchived = true
+
+	slackAPI.On("GetConversationsContext", a.context, conversationsPage("")).
+		Return([]slack.Channel{testChannel("C1", "general")}, "page-2", nil).Once()
+	slackAPI.On("GetConversationsContext", a.context, conversationsPage("page-2")).
+		Return([]slack.Channel{testChannel("C2", "deployments"), archived}, "", nil).Once()
+
+	expectPostMessage(slackAPI, a.context, "C2", 4).Return("C2", "1360782400.498405", nil).Twice()
+
+	err := a.Send("Hello", "#deployments")
+	require.NoError(t, err)
+
+	// The second message must use the cache.
+	err = a.Send("Hello again", "#deployments")
+	require.NoError(t, err)
+
+	err = a.Send("Hello", "#old-deployments")
+	assert.True(t, errors.Is(err, ErrArchivedChannel))
+	assert.EqualError(t, err, "#old-deployments: channel is archived")
+
+	err = a.Send("Hello", "#does-not-exist")
+	assert.True(t, errors.Is(err, ErrUnknownChannel))
+	assert.EqualError(t, err, "#does-not-exist: unknown channel")
+
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_SendToChannelNameInThread(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.channels.add(&slack.Channel{GroupConversation: slack.GroupConversation{
+		Conversation: slack.Conversation{ID: "C2"},
+		Name:         "deployments",
+	}})
+
+	expectPostMessage(slackAPI, a.context, "C2", 5).Return("C2", "1360782500.498405", nil)
+
+	err := a.Send("Hello", "#deployments/1360782400.498405")
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_SendToUserName(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("GetUsersContext", a.context).Return([]slack.User{
+		{ID: "U1", Name: "alice"},
+		{ID: "U2", Name: "jd"},
+	}, nil).Once()
+
+	dm := testChannel("D1", "")
+	slackAPI.On("OpenConversationContext", a.context, &slack.OpenConversationParameters{Users: []string{"U2"}}).
+		Return(&dm, false, true, nil).Once()
+
+	dm2 := testChannel("D2", "")
+	slackAPI.On("OpenConversationContext", a.context, &slack.OpenConversationParameters{Users: []string{"U1"}}).
+		Return(&dm2, false, true, nil).Once()
+
+	expectPostMessage(slackAPI, a.context, "D1", 4).Return("D1", "1360782400.498405", nil).Twice()
+	expectPostMessage(slackAPI, a.context, "D2", 4).Return("D2", "1360782400.498405", nil).Once()
+
+	require.NoError(t, a.Send("Hello", "@jd"))
+	require.NoError(t, a.Send("Hello again", "@jd"))
+
+	// The names of all listed users are known without listing them again.
+	require.NoError(t, a.Send("Hello", "@alice"))
+	slackAPI.AssertExpectations(t)
+
+	// All listed users are added to the user cache.
+	_, ok := a.users.Get("U1")
+	assert.True(t, ok)
+}
+
+func TestAdapter_SendToUnknownUserName(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("GetUsersContext", a.context).Return([]slack.User{
+		{ID: "U1", Name: "alice"},
+		{ID: "U2", Name: "jd", Deleted: true},
+	}, nil).Once()
+
+	err := a.Send("Hello", "@jd")
+	assert.True(t, errors.Is(err, ErrUnknownUser))
+	assert.EqualError(t, err, "@jd: unknown user")
+
+	// The users are not listed again for every unknown name.
+	err = a.Send("Hello", "@jd")
+	assert.True(t, errors.Is(err, ErrUnknownUser))
+	slackAPI.AssertExpectations(t)
+
+	// The users are listed again once the TTL expired.
+	slackAPI.On("GetUsersContext", a.context).Return([]slack.User{
+		{ID: "U1", Name: "alice"},
+	}, nil).Once()
+
+	a.channels.now = func() time.Time { return time.Now().Add(defaultChannelCacheTTL + time.Second) }
+	err = a.Send("Hello", "@jd")
+	assert.True(t, errors.Is(err, ErrUnknownUser))
+	slackAPI.AssertExpectations(t)
+}
+
+func TestChannelCache_Types(t *testing.T) {
+	c := newChannelCache(defaultChannelCacheTTL)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.setType("D1", ConversationTypeIM)
+	c.setUnknownType("G1")
+
+	typ, ok := c.getType("D1")
+	assert.True(t, ok)
+	assert.Equal(t, ConversationTypeIM, typ)
+
+	typ, ok = c.getType("G1")
+	assert.True(t, ok)
+	assert.Empty(t, typ)
+
+	// Types of channels that are not active anymore expire and are removed
+	// when new types are added.
+	now = now.Add(defaultChannelCacheTTL + time.Second)
+	c.setType("C1", ConversationTypePublic)
+	assert.Len(t, c.types, 1)
+
+	_, ok = c.getType("D1")
+	assert.False(t, ok)
+
+	_, ok = c.getType("G1")
+	assert.False(t, ok)
+}
+
+func TestChannelCache_Rename(t *testing.T) {
+	c := newChannelCache(defaultChannelCacheTTL)
+
+	channel := testChannel("C1", "deployments")
+	c.add(&channel)
+
+	renamed := testChannel("C1", "deploys")
+	c.add(&renamed)
+
+	_, ok := c.getByName("deployments")
+	assert.False(t, ok)
+
+	actual, ok := c.getByName("deploys")
+	require.True(t, ok)
+	assert.Equal(t, "C1", actual.ID)
+}
@@ -0,0 +1,175 @@
+package slack
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-joe/joe"
+)
+
+// A DeduplicationStore remembers the IDs of all events the EventsAPIServer has
+// received, so retried deliveries of the same event can be ignored.
+type DeduplicationStore interface {
+	// Seen marks the given event ID as seen and reports whether it has already
+	// been seen before.
+	Seen(eventID string) (bool, error)
+
+	// Forget removes the given event ID again, e.g. because the event was
+	// rejected and Slack will deliver it again.
+	Forget(eventID string) error
+}
+
+// memoryDeduplicationStore is a DeduplicationStore that keeps all event IDs in
+// memory until their TTL expires.
+type memoryDeduplicationStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	events    map[string]time.Time // maps event IDs to their expiration time
+	nextSweep time.Time
+}
+
+// NewMemoryDeduplicationStore returns a DeduplicationStore that keeps event
+// IDs in memory for the given duration. Slack retries failed deliveries up to
+// three times within roughly one hour, so the TTL should not be too short.
+func NewMemoryDeduplicationStore(ttl time.Duration) DeduplicationStore {
+	return &memoryDeduplicationStore{
+		ttl:    ttl,
+		now:    time.Now,
+		events: map[string]time.Time{},
+	}
+}
+
+func (s *memoryDeduplicationStore) Seen(eventID string) (bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		// Remove expired entries from time to time so the map does not grow
+		// without limit.
+		for id, expiration := range s.events {
+			if now.After(expiration) {
+				delete(s.events, id)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+
+	expiration, ok := s.events[eventID]
+	if ok && !now.After(expiration) {
+		return true, nil
+	}
+
+	s.events[eventID] = now.Add(s.ttl)
+	return false, nil
+}
+
+func (s *memoryDeduplicationStore) Forget(eventID string) error {
+	s.mu.Lock()
+	delete(s.events, eventID)
+	s.mu.Unlock()
+	return nil
+}
+
+// joeMemoryDeduplicationStore is a DeduplicationStore that keeps all event IDs
+// in a joe.Memory, so they survive restarts of the bot.
+type joeMemoryDeduplicationStore struct {
+	memory joe.Memory
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	nextSweep time.Time
+}
+
+const deduplicationKeyPrefix = "slack.events.seen."
+
+// NewJoeMemoryDeduplicationStore returns a DeduplicationStore that keeps event
+// IDs for the given duration in the given joe.Memory (e.g. a Redis or bolt
+// memory module).
+func NewJoeMemoryDeduplicationStore(memory joe.Memory, ttl time.Duration) DeduplicationStore {
+	return &joeMemoryDeduplicationStore{
+		memory: memory,
+		ttl:    ttl,
+		now:    time.Now,
+	}
+}
+
+func (s *joeMemoryDeduplicationStore) Seen(eventID string) (bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		err := s.sweep(now)
+		if err != nil {
+			return false, err
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+
+	key := deduplicationKeyPrefix + eventID
+	value, ok, err := s.memory.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	if ok && !s.expired(value, now) {
+		return true, nil
+	}
+
+	expiration := now.Add(s.ttl).Unix()
+	err = s.memory.Set(key, []byte(strconv.FormatInt(expiration, 10)))
+	return false, err
+}
+
+func (s *joeMemoryDeduplicationStore) Forget(eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.memory.Delete(deduplicationKeyPrefix + eventID)
+	return err
+}
+
+// sweep deletes all expired event IDs from the memory.
+func (s *joeMemoryDeduplicationStore) sweep(now time.Time) error {
+	keys, err := s.memory.Keys()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, deduplicationKeyPrefix) {
+			continue
+		}
+
+		value, ok, err := s.memory.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if ok && s.expired(value, now) {
+			if _, err := s.memory.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (*joeMemoryDeduplicationStore) expired(value []byte, now time.Time) bool {
+	expiration, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		// Treat corrupt entries as expired so they are overwritten.
+		return true
+	}
+
+	return now.Unix() > expiration
+}
@@ -0,0 +1,126 @@
+package slack
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDeduplicationStore(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryDeduplicationStore(time.Minute).(*memoryDeduplicationStore)
+	s.now = func() time.Time { return now }
+
+	seen, err := s.Seen("Ev01")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	seen, err = s.Seen("Ev01")
+	require.NoError(t, err)
+	assert.True(t, seen)
+
+	seen, err = s.Seen("Ev02")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	now = now.Add(2 * time.Minute)
+	seen, err = s.Seen("Ev01")
+	require.NoError(t, err)
+	assert.False(t, seen, "entries should expire after their TTL")
+
+	assert.Len(t, s.events, 1, "expired entries should be removed")
+
+	require.NoError(t, s.Forget("Ev01"))
+	seen, err = s.Seen("Ev01")
+	require.NoError(t, err)
+	assert.False(t, seen, "forgotten entries should not be seen")
+}
+
+func TestJoeMemoryDeduplicationStore(t *testing.T) {
+	now := time.Now()
+	mem := newTestMemory()
+	require.NoError(t, mem.Set("unrelated", []byte("value")))
+
+	s := NewJoeMemoryDeduplicationStore(mem, time.Minute).(*joeMemoryDeduplicationStore)
+	s.now = func() time.Time { return now }
+
+	seen, err := s.Seen("Ev01")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	// A new store simulates a restart of the bot.
+	s = NewJoeMemoryDeduplicationStore(mem, time.Minute).(*joeMemoryDeduplicationStore)
+	s.now = func() time.Time { return now }
+
+	seen, err = s.Seen("Ev01")
+	require.NoError(t, err)
+	assert.True(t, seen)
+
+	now = now.Add(2 * time.Minute)
+	seen, err = s.Seen("Ev02")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	keys, err := mem.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"slack.events.seen.Ev02", "unrelated"}, keys)
+
+	require.NoError(t, s.Forget("Ev02"))
+	keys, err = mem.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unrelated"}, keys)
+}
+
+// testMemory is a simple in-memory joe.Memory for unit tests.
+type testMemory struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+var _ joe.Memory = new(testMemory)
+
+func newTestMemory() *testMemory {
+	return &testMemory{data: map[string][]byte{}}
+}
+
+func (m *testMemory) Set(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *testMemory) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.data[key]
+	return value, ok, nil
+}
+
+func (m *testMemory) Delete(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.data[key]
+	delete(m.data, key)
+	return ok, nil
+}
+
+func (m *testMemory) Keys() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.data))
+	for k := range m.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *testMemory) Close() error {
+	return nil
+}
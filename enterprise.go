@@ -0,0 +1,82 @@
+package slack
+
+import (
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// errUserNotFound is the error of the Slack API if a user cannot be looked up,
+// which happens for users of other organizations in shared channels.
+const errUserNotFound = "user_not_found"
+
+// eventChannelType translates the channel_type field of Events API message
+// events into a ConversationType. It returns an empty string if the type is
+// unknown.
+func eventChannelType(channelType string) ConversationType {
+	switch channelType {
+	case "channel":
+		return ConversationTypePublic
+	case "group":
+		return ConversationTypePrivate
+	case "im":
+		return ConversationTypeIM
+	case "mpim":
+		return ConversationTypeMPIM
+	default:
+		return ""
+	}
+}
+
+// isDirectMessage returns true if the message was sent in a direct message
+// conversation with the bot. With Enterprise Grid, the ID of a conversation
+// does not reliably tell its type, so the type is taken from the channel_type
+// of the event if Slack sent one and otherwise looked up via the Slack API.
+func (a *BotAdapter) isDirectMessage(ev *slack.MessageEvent) bool {
+	return a.conversationTypeOf(ev.Channel) == ConversationTypeIM
+}
+
+// conversationTypeOf returns the type of the conversation with the given ID
+// or an empty string if it cannot be looked up. Failed lookups are not
+// repeated until the channel cache TTL expired, since bots without the
+// necessary scopes would otherwise request it for every message.
+func (a *BotAdapter) conversationTypeOf(channelID string) ConversationType {
+	if typ, ok := a.channels.getType(channelID); ok {
+		return typ
+	}
+
+	channel, err := a.slackChannel(channelID)
+	if err != nil {
+		a.logger.Debug("Failed to get type of conversation",
+			zap.String("channel_id", channelID),
+			zap.Error(err),
+		)
+		a.channels.setUnknownType(channelID)
+		return ""
+	}
+
+	typ := conversationType(channel)
+	a.channels.setType(channelID, typ)
+	return typ
+}
+
+// isExternalUser returns true if the user belongs to another organization
+// than the bot, e.g. because they are a member of a channel that is shared via
+// Slack Connect. Users of other workspaces of the same Enterprise Grid
+// organization are not considered external.
+func (a *BotAdapter) isExternalUser(user *slack.User) bool {
+	if user.IsStranger {
+		return true
+	}
+
+	if a.enterpriseID != "" && user.Enterprise.EnterpriseID != "" {
+		return user.Enterprise.EnterpriseID != a.enterpriseID
+	}
+
+	return a.teamID != "" && user.TeamID != "" && user.TeamID != a.teamID
+}
+
+// unknownUser returns the placeholder that is cached for users that cannot be
+// looked up via the Slack API. They are treated as external users.
+func unknownUser(userID string) *slack.User {
+	return &slack.User{ID: userID, IsStranger: true}
+}
@@ -0,0 +1,182 @@
+package slack
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventChannelType(t *testing.T) {
+	assert.Equal(t, ConversationTypePublic, eventChannelType("channel"))
+	assert.Equal(t, ConversationTypePrivate, eventChannelType("group"))
+	assert.Equal(t, ConversationTypeIM, eventChannelType("im"))
+	assert.Equal(t, ConversationTypeMPIM, eventChannelType("mpim"))
+	assert.Equal(t, ConversationType(""), eventChannelType("foo"))
+}
+
+func TestAdapter_DirectMessageWithoutPrefix(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	channel := &slack.Channel{}
+	channel.ID = "G024BE91L"
+	channel.IsIM = true
+	slackAPI.On("GetConversationInfoContext", a.context, "G024BE91L", false).Return(channel, nil).Once()
+	slackAPI.On("GetConversationInfoContext", a.context, "G1H9RESGL", false).Return(nil, errors.New("missing_scope")).Once()
+	expectConversation(slackAPI, a.context, "D1H9RESGL", false).Once()
+
+	newMessage := func(channel string) *slack.MessageEvent {
+		return &slack.MessageEvent{Msg: slack.Msg{
+			Channel:   channel,
+			User:      "U1234",
+			Text:      "Hello",
+			Timestamp: "1360782400.498405",
+		}}
+	}
+
+	events := processWorkspaceEvents(t, a,
+		// The type of the conversation is looked up once and then cached.
+		slackEvent{Data: newMessage("G024BE91L")},
+		slackEvent{Data: newMessage("G024BE91L")},
+		// Conversations of unknown type are not treated as direct messages
+		// and failed lookups are not repeated.
+		slackEvent{Data: newMessage("G1H9RESGL")},
+		slackEvent{Data: newMessage("G1H9RESGL")},
+		// The prefix of the ID does not tell the type of the conversation.
+		slackEvent{Data: newMessage("D1H9RESGL")},
+		// The type from the event does not need to be looked up.
+		slackEvent{Data: newMessage("C024BE91L"), ChannelType: ConversationTypeIM},
+		slackEvent{Data: newMessage("D024BE91L"), ChannelType: ConversationTypeMPIM},
+	)
+
+	require.Len(t, events, 3)
+	assert.Equal(t, "G024BE91L", events[0].(joe.ReceiveMessageEvent).Channel)
+	assert.Equal(t, "G024BE91L", events[1].(joe.ReceiveMessageEvent).Channel)
+	assert.Equal(t, "C024BE91L", events[2].(joe.ReceiveMessageEvent).Channel)
+	slackAPI.AssertExpectations(t)
+
+	// The lookup is repeated once the failure expired.
+	a.channels.now = func() time.Time { return time.Now().Add(defaultChannelCacheTTL + time.Second) }
+	_, ok := a.channels.getType("G1H9RESGL")
+	assert.False(t, ok)
+}
+
+func TestAdapter_IsExternalUser(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	a.teamID = "T1"
+
+	assert.False(t, a.isExternalUser(&slack.User{ID: "U1", TeamID: "T1"}))
+	assert.True(t, a.isExternalUser(&slack.User{ID: "U2", TeamID: "T2"}))
+	assert.True(t, a.isExternalUser(&slack.User{ID: "U3", IsStranger: true}))
+	assert.False(t, a.isExternalUser(&slack.User{ID: "U4"}))
+
+	// Users of other workspaces of the same organization are not external.
+	a.enterpriseID = "E1"
+	user := &slack.User{ID: "U2", TeamID: "T2"}
+	user.Enterprise.EnterpriseID = "E1"
+	assert.False(t, a.isExternalUser(user))
+
+	user.Enterprise.EnterpriseID = "E2"
+	assert.True(t, a.isExternalUser(user))
+}
+
+func TestAdapter_UnknownUser(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	slackAPI.On("GetUserInfoContext", a.context, "U1234").Return(nil, errors.New(errUserNotFound)).Once()
+
+	user, err := a.slackUser("U1234")
+	require.NoError(t, err)
+	assert.Equal(t, unknownUser("U1234"), user)
+
+	// The placeholder is cached so the user is not requested again.
+	user, err = a.slackUser("U1234")
+	require.NoError(t, err)
+	assert.True(t, a.isExternalUser(user))
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_EnterpriseContext(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.teamID = "T1"
+	a.enterpriseID = "E1"
+	a.messageMetadata = true
+
+	author := &slack.User{ID: "U1234", Name: "jd", TeamID: "T2"}
+	author.Enterprise.EnterpriseID = "E2"
+	slackAPI.On("GetUserInfoContext", a.context, "U1234").Return(author, nil)
+
+	channel := &slack.Channel{}
+	channel.ID = "C1H9RESGL"
+	channel.Name = "partners"
+	channel.IsChannel = true
+	channel.IsShared = true
+	channel.IsExtShared = true
+	slackAPI.On("GetConversationInfoContext", a.context, "C1H9RESGL", false).Return(channel, nil)
+
+	evt := &slack.MessageEvent{Msg: slack.Msg{
+		Channel:   "C1H9RESGL",
+		User:      "U1234",
+		Text:      "<@42> hello",
+		Timestamp: "1360782400.498405",
+	}}
+
+	events := processTestEvents(t, a, evt)
+	require.Len(t, events, 1)
+
+	msg := events[0].(joe.ReceiveMessageEvent)
+	expected := &EnrichedMessageEvent{
+		MessageEvent: evt,
+		Author: AuthorInfo{
+			ID:           "U1234",
+			Name:         "jd",
+			TeamID:       "T2",
+			EnterpriseID: "E2",
+			IsExternal:   true,
+		},
+		Conversation: ConversationInfo{
+			ID:          "C1H9RESGL",
+			Name:        "partners",
+			Type:        ConversationTypePublic,
+			IsShared:    true,
+			IsExtShared: true,
+		},
+		TeamID:       "T1",
+		EnterpriseID: "E1",
+	}
+	assert.Equal(t, expected, msg.Data)
+}
+
+func TestEventsAPIServer_DirectMessageChannelType(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t)
+
+	req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+		Type: slackevents.CallbackEvent,
+		InnerEvent: rawJSON(slackevents.MessageEvent{
+			Type:        slackevents.Message,
+			Channel:     "C024BE91L",
+			ChannelType: "im",
+			User:        "U1234",
+			Text:        "Hello World!",
+			TimeStamp:   "1595070350.000100",
+		}),
+	}))
+
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+
+	msg, ok := events[0].(joe.ReceiveMessageEvent)
+	require.True(t, ok)
+	assert.Equal(t, "Hello World!", msg.Text)
+	assert.Equal(t, "C024BE91L", msg.Channel)
+}
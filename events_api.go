@@ -2,10 +2,17 @@ package slack
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-joe/joe"
 	"github.com/slack-go/slack"
@@ -23,8 +30,35 @@ type EventsAPIServer struct {
 	http *http.Server
 	conf EventsAPIConfig
 	opts []slackevents.Option
+
+	apiURL string // used for the OAuth flow
+
+	verificationToken string
+
+	// Events are acknowledged immediately and then processed asynchronously
+	// via this queue so slow handlers do not delay the HTTP response.
+	queue     chan slackEvent
+	queueDone chan struct{}
+
+	// The queue is only closed while no HTTP handler is sending to it, which
+	// is guarded by queueMu. Handlers that wait for room in the queue give up
+	// as soon as closing is closed.
+	queueMu     sync.RWMutex
+	queueClosed bool
+	closing     chan struct{}
+
+	// abandon is closed if the queued events could not be processed within
+	// the shutdown timeout, e.g. because the event processing loop is not
+	// running anymore.
+	abandon   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
 }
 
+// defaultQueueDrainTimeout is the maximum time Close waits for the queued
+// events to be processed if no ShutdownTimeout is configured.
+const defaultQueueDrainTimeout = 10 * time.Second
+
 // EventsAPIAdapter returns a new EventsAPIServer as joe.Module.
 // If you want to use the slack RTM API instead (i.e. using web sockets), you
 // should use the slack.Adapter(…) function instead.
@@ -50,6 +84,10 @@ func EventsAPIAdapter(listenAddr, token, verificationToken string, opts ...Optio
 // using the events API. Note that you will usually configure this type of slack
 // adapter as joe.Module (i.e. using the EventsAPIAdapter function of this package).
 func NewEventsAPIServer(ctx context.Context, listenAddr string, conf Config) (*EventsAPIServer, error) {
+	if conf.EventsAPI.OAuth.ClientID != "" && conf.EventsAPI.Installations == nil {
+		return nil, errors.New("OAuth requires an installation store")
+	}
+
 	events := make(chan slackEvent)
 	client := slack.New(conf.Token, conf.slackOptions()...)
 	adapter, err := newAdapter(ctx, client, nil, events, conf)
@@ -60,15 +98,62 @@ func NewEventsAPIServer(ctx context.Context, listenAddr string, conf Config) (*E
 	a := &EventsAPIServer{
 		BotAdapter: adapter,
 		conf:       conf.EventsAPI,
+		queueDone:  make(chan struct{}),
+		closing:    make(chan struct{}),
+		abandon:    make(chan struct{}),
+		apiURL:     conf.apiURL(),
+
+		verificationToken: conf.VerificationToken,
 	}
 
-	a.opts = append(a.opts, slackevents.OptionVerifyToken(
-		&slackevents.TokenComparator{
-			VerificationToken: conf.VerificationToken,
-		},
-	))
+	if a.conf.SigningSecret != "" {
+		// Requests are authenticated via their signature in the HTTP handler.
+		a.opts = append(a.opts, slackevents.OptionNoVerifyToken())
+	} else {
+		a.opts = append(a.opts, slackevents.OptionVerifyToken(
+			&slackevents.TokenComparator{
+				VerificationToken: conf.VerificationToken,
+			},
+		))
+	}
+
+	if a.conf.SignatureMaxAge == 0 {
+		a.conf.SignatureMaxAge = 5 * time.Minute
+	}
+
+	if a.conf.QueueSize <= 0 {
+		a.conf.QueueSize = 100
+	}
+
+	if a.conf.Deduplication == nil {
+		a.conf.Deduplication = NewMemoryDeduplicationStore(time.Hour)
+	}
+
+	if a.conf.OAuth.InstallPath == "" {
+		a.conf.OAuth.InstallPath = defaultOAuthInstallPath
+	}
+
+	if a.conf.OAuth.RedirectPath == "" {
+		a.conf.OAuth.RedirectPath = defaultOAuthRedirectPath
+	}
+
+	a.queue = make(chan slackEvent, a.conf.QueueSize)
+	go a.processQueue()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", a.httpHandler)
+	if a.conf.InteractionsPath != "" {
+		mux.HandleFunc(a.conf.InteractionsPath, a.interactionsHandler)
+	}
+	if a.conf.SlashCommandsPath != "" {
+		mux.HandleFunc(a.conf.SlashCommandsPath, a.slashCommandsHandler)
+	}
+	if a.conf.OAuth.ClientID != "" {
+		mux.HandleFunc(a.conf.OAuth.InstallPath, a.oauthInstallHandler)
+		mux.HandleFunc(a.conf.OAuth.RedirectPath, a.oauthRedirectHandler)
+	}
 
-	var handler http.Handler = http.HandlerFunc(a.httpHandler)
+	var handler http.Handler = mux
 	if conf.EventsAPI.Middleware != nil {
 		handler = conf.EventsAPI.Middleware(handler)
 	}
@@ -107,15 +192,16 @@ func (a *EventsAPIServer) startHTTPServer() {
 }
 
 func (a *EventsAPIServer) httpHandler(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		a.logger.Error("Failed to read request body", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	body, ok := a.readRequest(w, r)
+	if !ok {
 		return
 	}
 
+	a.sampler.sampleRaw(payloadType(body), body)
+
 	eventsAPIEvent, err := slackevents.ParseEvent(body, a.opts...)
 	if err != nil {
+		a.sampler.sampleUnknown(payloadType(body), body)
 		a.logger.Error("Failed to parse slack event", zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -126,7 +212,17 @@ func (a *EventsAPIServer) httpHandler(w http.ResponseWriter, r *http.Request) {
 		a.handleURLVerification(body, w)
 
 	case slackevents.CallbackEvent:
-		a.handleEvent(eventsAPIEvent.InnerEvent)
+		callback := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent)
+		if a.isDuplicate(callback.EventID, r.Header) {
+			return
+		}
+
+		teamID, enterpriseID := callbackWorkspace(body)
+		if !a.handleEvent(eventsAPIEvent.InnerEvent, teamID, enterpriseID, w) {
+			// Slack delivers rejected events again, which must not be
+			// ignored as duplicates.
+			a.forgetEvent(callback.EventID)
+		}
 
 	default:
 		a.logger.Error("Received unknown top level event type",
@@ -135,6 +231,78 @@ func (a *EventsAPIServer) httpHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// readRequest reads the body of a request from Slack and verifies its
+// signature if a signing secret is configured. If the request cannot be
+// processed, an error status is written and false is returned.
+func (a *EventsAPIServer) readRequest(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if a.conf.NoRetry {
+		w.Header().Set("X-Slack-No-Retry", "1")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.logger.Error("Failed to read request body", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if a.conf.SigningSecret != "" {
+		err = a.verifySignature(r.Header, body)
+		if err != nil {
+			a.logger.Warn("Rejected request with invalid signature", zap.Error(err))
+			w.WriteHeader(http.StatusUnauthorized)
+			return nil, false
+		}
+	}
+
+	return body, true
+}
+
+// verifyToken checks the legacy verification token of requests that are not
+// parsed via the slackevents package. If a signing secret is configured, the
+// token is not checked since the request signature was verified already.
+func (a *EventsAPIServer) verifyToken(token string) bool {
+	if a.conf.SigningSecret != "" {
+		return true
+	}
+
+	return slackevents.TokenComparator{VerificationToken: a.verificationToken}.Verify(token)
+}
+
+// verifySignature checks the signature of a request using the signing secret.
+// See https://api.slack.com/authentication/verifying-requests-from-slack
+func (a *EventsAPIServer) verifySignature(header http.Header, body []byte) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	signature := header.Get("X-Slack-Signature")
+	if timestamp == "" || signature == "" {
+		return errors.New("missing signature headers")
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp: %w", err)
+	}
+
+	age := time.Since(time.Unix(sec, 0))
+	if age < 0 {
+		age = -age
+	}
+	if age > a.conf.SignatureMaxAge {
+		return fmt.Errorf("request timestamp is outside of the allowed window of %s", a.conf.SignatureMaxAge)
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.conf.SigningSecret))
+	_, _ = fmt.Fprintf(mac, "v0:%s:", timestamp)
+	_, _ = mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
 func (a *EventsAPIServer) handleURLVerification(req []byte, resp http.ResponseWriter) {
 	a.logger.Info("Received URL verification challenge request")
 
@@ -155,18 +323,184 @@ func (a *EventsAPIServer) handleURLVerification(req []byte, resp http.ResponseWr
 	resp.WriteHeader(http.StatusOK)
 }
 
-func (a *EventsAPIServer) handleEvent(innerEvent slackevents.EventsAPIInnerEvent) {
+// isDuplicate checks whether we have already received the event with the
+// given ID before, which happens when Slack retries to deliver it.
+func (a *EventsAPIServer) isDuplicate(eventID string, header http.Header) bool {
+	if eventID == "" {
+		return false
+	}
+
+	seen, err := a.conf.Deduplication.Seen(eventID)
+	if err != nil {
+		// Rather process an event twice than not at all.
+		a.logger.Error("Failed to check if event was already received",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	if seen {
+		a.logger.Debug("Ignoring duplicate slack event",
+			zap.String("event_id", eventID),
+			zap.String("retry_num", header.Get("X-Slack-Retry-Num")),
+			zap.String("retry_reason", header.Get("X-Slack-Retry-Reason")),
+		)
+	}
+
+	return seen
+}
+
+// forgetEvent removes the event ID from the deduplication store again.
+func (a *EventsAPIServer) forgetEvent(eventID string) {
+	if eventID == "" {
+		return
+	}
+
+	err := a.conf.Deduplication.Forget(eventID)
+	if err != nil {
+		a.logger.Error("Failed to forget rejected event",
+			zap.String("event_id", eventID),
+			zap.Error(err),
+		)
+	}
+}
+
+// handleEvent queues the inner event of an Events API callback. It returns
+// false if the event was rejected and Slack will deliver it again.
+func (a *EventsAPIServer) handleEvent(innerEvent slackevents.EventsAPIInnerEvent, teamID, enterpriseID string, w http.ResponseWriter) bool {
+	evt, ok := a.eventsAPIEvent(innerEvent)
+	if !ok {
+		return true
+	}
+
+	evt.TeamID = teamID
+	evt.EnterpriseID = enterpriseID
+	_, rejected := a.tryEnqueue(evt, w, true)
+	return !rejected
+}
+
+// enqueue adds the event to the queue of events that are waiting to be
+// processed. If the queue is full, the configured QueuePolicy decides what
+// happens with the event. The function returns true if the event was queued.
+func (a *EventsAPIServer) enqueue(evt slackEvent, w http.ResponseWriter) bool {
+	queued, _ := a.tryEnqueue(evt, w, true)
+	return queued
+}
+
+// tryEnqueue is like enqueue but additionally reports whether the event was
+// rejected with "503 Service Unavailable", so Slack will deliver it again.
+// If ack is false, the request is not acknowledged while waiting for room in
+// the queue, so the caller can still write its own response.
+func (a *EventsAPIServer) tryEnqueue(evt slackEvent, w http.ResponseWriter, ack bool) (queued, rejected bool) {
+	a.queueMu.RLock()
+	defer a.queueMu.RUnlock()
+
+	if a.queueClosed {
+		// Handlers can outlive the shutdown of the HTTP server if the
+		// shutdown timeout expired, so Slack should deliver the event again.
+		a.logger.Warn("Rejecting slack event because server is shutting down", zap.String("type", evt.Type))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return false, true
+	}
+
+	select {
+	case a.queue <- evt:
+		a.logger.Debug("Queued slack event",
+			zap.String("type", evt.Type),
+			zap.Int("queue_depth", len(a.queue)),
+		)
+		return true, false
+	default:
+	}
+
+	logger := a.logger.With(
+		zap.String("type", evt.Type),
+		zap.Int("queue_depth", len(a.queue)),
+		zap.Int("queue_size", cap(a.queue)),
+	)
+
+	switch a.conf.QueuePolicy {
+	case QueuePolicyDrop:
+		logger.Warn("Dropping slack event because event queue is full")
+		return false, false
+
+	case QueuePolicyReject:
+		logger.Warn("Rejecting slack event because event queue is full")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return false, true
+
+	default:
+		logger.Warn("Event queue is full, waiting for event processing")
+
+		if ack {
+			// Acknowledge the event before we block so Slack does not retry.
+			w.WriteHeader(http.StatusOK)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+
+		select {
+		case a.queue <- evt:
+			return true, false
+		case <-a.closing:
+			// The event was acknowledged already so it is lost.
+			logger.Error("Dropping slack event because server is shutting down")
+			return false, false
+		}
+	}
+}
+
+// processQueue forwards all queued events to the event processing loop.
+func (a *EventsAPIServer) processQueue() {
+	defer close(a.queueDone)
+	for evt := range a.queue {
+		select {
+		case a.events <- evt:
+		case <-a.abandon:
+			// The queue is closed already when the events are abandoned.
+			dropped := 1
+			for range a.queue {
+				dropped++
+			}
+
+			a.logger.Error("Dropped queued slack events during shutdown", zap.Int("events", dropped))
+			return
+		}
+	}
+}
+
+// eventsAPIEvent translates an inner event of the Events API into the
+// slackEvent type that is consumed by BotAdapter.handleSlackEvents. It is used
+// by all adapters that receive Events API payloads (i.e. the EventsAPIServer
+// and the SocketModeClient).
+func (a *BotAdapter) eventsAPIEvent(innerEvent slackevents.EventsAPIInnerEvent) (slackEvent, bool) {
 	switch ev := innerEvent.Data.(type) {
 	case *slackevents.MessageEvent:
-		a.handleMessageEvent(ev)
+		return slackEvent{Type: ev.Type, Data: newMessageEvent(ev), ChannelType: eventChannelType(ev.ChannelType)}, true
 
 	case *slackevents.AppMentionEvent:
-		a.handleAppMentionEvent(ev)
+		return slackEvent{Type: ev.Type, Data: newAppMentionEvent(ev)}, true
 
 	case *slackevents.ReactionAddedEvent:
-		a.handleReactionAddedEvent(ev)
+		return slackEvent{Type: ev.Type, Data: newReactionAddedEvent(ev)}, true
+
+	case *slackevents.ReactionRemovedEvent:
+		return slackEvent{Type: ev.Type, Data: newReactionRemovedEvent(ev)}, true
+
+	case *slackevents.MemberJoinedChannelEvent:
+		return slackEvent{Type: ev.Type, Data: (*slack.MemberJoinedChannelEvent)(ev)}, true
+
+	case *slackevents.MemberLeftChannelEvent:
+		return slackEvent{Type: ev.Type, Data: (*slack.MemberLeftChannelEvent)(ev)}, true
+
+	case *slack.UserChangeEvent, *slack.FileSharedEvent, *slack.ChannelLeftEvent, *slack.GroupLeftEvent, *slack.TeamJoinEvent:
+		// RTM event types are passed through unchanged.
+		return slackEvent{Type: innerEvent.Type, Data: ev}, true
 
 	default:
+		a.sampler.sampleUnknown(innerEvent.Type, innerEvent.Data)
 		if a.logUnknownMessageTypes {
 			a.logger.Error("Received unknown event type",
 				zap.String("type", innerEvent.Type),
@@ -174,10 +508,11 @@ func (a *EventsAPIServer) handleEvent(innerEvent slackevents.EventsAPIInnerEvent
 				zap.String("go_type", fmt.Sprintf("%T", innerEvent.Data)),
 			)
 		}
+		return slackEvent{}, false
 	}
 }
 
-func (a *EventsAPIServer) handleMessageEvent(ev *slackevents.MessageEvent) {
+func newMessageEvent(ev *slackevents.MessageEvent) *slack.MessageEvent {
 	var edited *slack.Edited
 	if ev.Edited != nil {
 		edited = &slack.Edited{
@@ -194,46 +529,86 @@ func (a *EventsAPIServer) handleMessageEvent(ev *slackevents.MessageEvent) {
 		}
 	}
 
-	a.events <- slackEvent{
-		Type: ev.Type,
-		Data: &slack.MessageEvent{
-			Msg: slack.Msg{
-				Type:            ev.Type,
-				Channel:         ev.Channel,
-				User:            ev.User,
-				Text:            ev.Text,
-				Timestamp:       ev.TimeStamp,
-				ThreadTimestamp: ev.ThreadTimeStamp,
-				Edited:          edited,
-				SubType:         ev.SubType,
-				EventTimestamp:  ev.EventTimeStamp.String(),
-				BotID:           ev.BotID,
-				Username:        ev.Username,
-				Icons:           icons,
-			},
+	msg := &slack.MessageEvent{
+		Msg: slack.Msg{
+			Type:            ev.Type,
+			Channel:         ev.Channel,
+			User:            ev.User,
+			Text:            ev.Text,
+			Timestamp:       ev.TimeStamp,
+			ThreadTimestamp: ev.ThreadTimeStamp,
+			Edited:          edited,
+			SubType:         ev.SubType,
+			EventTimestamp:  ev.EventTimeStamp.String(),
+			BotID:           ev.BotID,
+			Username:        ev.Username,
+			Icons:           icons,
+			Upload:          ev.Upload,
+			Files:           newFiles(ev.Files),
 		},
 	}
+
+	// Edited and deleted messages carry the new and old versions of the message.
+	if ev.Message != nil {
+		msg.SubMessage = &newMessageEvent(ev.Message).Msg
+	}
+	if ev.PreviousMessage != nil {
+		msg.PreviousMessage = &newMessageEvent(ev.PreviousMessage).Msg
+	}
+
+	return msg
 }
 
-func (a *EventsAPIServer) handleAppMentionEvent(ev *slackevents.AppMentionEvent) {
-	a.events <- slackEvent{
-		Type: ev.Type,
-		Data: &slack.MessageEvent{
-			Msg: slack.Msg{
-				Type:            ev.Type,
-				User:            ev.User,
-				Text:            ev.Text,
-				Timestamp:       ev.TimeStamp,
-				ThreadTimestamp: ev.ThreadTimeStamp,
-				Channel:         ev.Channel,
-				EventTimestamp:  ev.EventTimeStamp.String(),
-				BotID:           ev.BotID,
-			},
+func newFiles(files []slackevents.File) []slack.File {
+	if len(files) == 0 {
+		return nil
+	}
+
+	result := make([]slack.File, len(files))
+	for i, f := range files {
+		result[i] = slack.File{
+			ID:                 f.ID,
+			Created:            slack.JSONTime(f.Created),
+			Timestamp:          slack.JSONTime(f.Timestamp),
+			Name:               f.Name,
+			Title:              f.Title,
+			Mimetype:           f.Mimetype,
+			Filetype:           f.Filetype,
+			PrettyType:         f.PrettyType,
+			User:               f.User,
+			Editable:           f.Editable,
+			Size:               f.Size,
+			Mode:               f.Mode,
+			IsExternal:         f.IsExternal,
+			ExternalType:       f.ExternalType,
+			IsPublic:           f.IsPublic,
+			PublicURLShared:    f.PublicURLShared,
+			URLPrivate:         f.URLPrivate,
+			URLPrivateDownload: f.URLPrivateDownload,
+			Permalink:          f.Permalink,
+			PermalinkPublic:    f.PermalinkPublic,
+		}
+	}
+
+	return result
+}
+
+func newAppMentionEvent(ev *slackevents.AppMentionEvent) *slack.MessageEvent {
+	return &slack.MessageEvent{
+		Msg: slack.Msg{
+			Type:            ev.Type,
+			User:            ev.User,
+			Text:            ev.Text,
+			Timestamp:       ev.TimeStamp,
+			ThreadTimestamp: ev.ThreadTimeStamp,
+			Channel:         ev.Channel,
+			EventTimestamp:  ev.EventTimeStamp.String(),
+			BotID:           ev.BotID,
 		},
 	}
 }
 
-func (a *EventsAPIServer) handleReactionAddedEvent(ev *slackevents.ReactionAddedEvent) {
+func newReactionAddedEvent(ev *slackevents.ReactionAddedEvent) *slack.ReactionAddedEvent {
 	evt := &slack.ReactionAddedEvent{
 		Type:           ev.Type,
 		User:           ev.User,
@@ -246,14 +621,26 @@ func (a *EventsAPIServer) handleReactionAddedEvent(ev *slackevents.ReactionAdded
 	evt.Item.Channel = ev.Item.Channel
 	evt.Item.Timestamp = ev.Item.Timestamp
 
-	a.events <- slackEvent{
-		Type: ev.Type,
-		Data: evt,
-	}
+	return evt
+}
+
+func newReactionRemovedEvent(ev *slackevents.ReactionRemovedEvent) *slack.ReactionRemovedEvent {
+	// Both reaction event types share the same underlying structure.
+	evt := newReactionAddedEvent((*slackevents.ReactionAddedEvent)(ev))
+	return (*slack.ReactionRemovedEvent)(evt)
 }
 
-// Close shuts down the disconnects the adapter from the slack API.
+// Close shuts down the disconnects the adapter from the slack API. It is safe
+// to call Close multiple times.
 func (a *EventsAPIServer) Close() error {
+	a.closeOnce.Do(func() {
+		a.closeErr = a.close()
+	})
+
+	return a.closeErr
+}
+
+func (a *EventsAPIServer) close() error {
 	ctx := context.Background()
 	if a.conf.ShutdownTimeout > 0 {
 		var cancel func()
@@ -263,8 +650,29 @@ func (a *EventsAPIServer) Close() error {
 
 	err := a.http.Shutdown(ctx)
 
-	// After we are sure we do not get any new events from the HTTP server, we
-	// must stop event processing loop by closing the channel.
+	// If the shutdown timed out, some handlers may still wait for room in the
+	// queue. We stop them and then close the queue once no handler can send to
+	// it anymore. Afterwards we process all queued events and stop the event
+	// processing loop by closing the channel. If the events are not processed
+	// in time, they are dropped so Close does not block forever.
+	close(a.closing)
+	a.queueMu.Lock()
+	a.queueClosed = true
+	close(a.queue)
+	a.queueMu.Unlock()
+
+	drainTimeout := a.conf.ShutdownTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultQueueDrainTimeout
+	}
+
+	select {
+	case <-a.queueDone:
+	case <-time.After(drainTimeout):
+		close(a.abandon)
+		<-a.queueDone
+	}
+
 	close(a.events)
 
 	return err
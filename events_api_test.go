@@ -3,13 +3,18 @@ package slack
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-joe/joe"
 	"github.com/go-joe/joe/joetest"
@@ -24,6 +29,13 @@ import (
 )
 
 func newTestEventsAPIServer(t *testing.T, optionalConf ...Config) (_ *EventsAPIServer, finish func() (events []interface{})) {
+	s := newTestEventsAPIServerWithoutBrain(t, optionalConf...)
+	return s, startTestBrain(t, s)
+}
+
+// newTestEventsAPIServerWithoutBrain creates a new EventsAPIServer without
+// starting its event processing loop.
+func newTestEventsAPIServerWithoutBrain(t *testing.T, optionalConf ...Config) *EventsAPIServer {
 	var conf Config
 	if len(optionalConf) > 0 {
 		conf = optionalConf[0]
@@ -48,6 +60,11 @@ func newTestEventsAPIServer(t *testing.T, optionalConf ...Config) (_ *EventsAPIS
 	s, err := NewEventsAPIServer(ctx, "127.0.0.1:0", conf)
 	require.NoError(t, err)
 
+	return s
+}
+
+// startTestBrain starts the event processing loop of the given server.
+func startTestBrain(t *testing.T, s *EventsAPIServer) (finish func() (events []interface{})) {
 	brain := joetest.NewBrain(t)
 	done := make(chan bool)
 	go func() {
@@ -55,14 +72,12 @@ func newTestEventsAPIServer(t *testing.T, optionalConf ...Config) (_ *EventsAPIS
 		done <- true
 	}()
 
-	finish = func() []interface{} {
+	return func() []interface{} {
 		assert.NoError(t, s.Close())
 		<-done // wait until event processing loop has stopped
 		brain.Finish()
 		return brain.RecordedEvents()
 	}
-
-	return s, finish
 }
 
 func TestEventsAPIServer_HandleMessageEvent(t *testing.T) {
@@ -73,6 +88,7 @@ func TestEventsAPIServer_HandleMessageEvent(t *testing.T) {
 		InnerEvent: rawJSON(slackevents.MessageEvent{
 			Type:            slackevents.Message,
 			Channel:         "D023BB3L2",
+			ChannelType:     "im",
 			User:            "U1234",
 			Username:        "fgrosse",
 			Text:            "Hello World!",
@@ -107,7 +123,7 @@ func TestEventsAPIServer_HandleAppMentionEvent(t *testing.T) {
 			Type:            slackevents.AppMention,
 			Channel:         "D023BB3L2",
 			User:            "U1234",
-			Text:            "Hey @joe!",
+			Text:            "Hey <@test-userID>!",
 			TimeStamp:       "1595070350",
 			ThreadTimeStamp: "1595070351",
 			EventTimeStamp:  "1595070352",
@@ -127,7 +143,7 @@ func TestEventsAPIServer_HandleAppMentionEvent(t *testing.T) {
 	actualRawData := actual.Data
 	actual.Data = nil // validated separately
 	assert.IsType(t, new(slack.MessageEvent), actualRawData)
-	assert.Equal(t, "Hey @joe!", actualRawData.(*slack.MessageEvent).Text)
+	assert.Equal(t, "Hey <@test-userID>!", actualRawData.(*slack.MessageEvent).Text)
 }
 
 func TestEventsAPIServer_HandleReactionAddedEvent(t *testing.T) {
@@ -162,6 +178,38 @@ func TestEventsAPIServer_HandleReactionAddedEvent(t *testing.T) {
 	assert.Equal(t, "+1", actual.Reaction.Shortcode)
 }
 
+func TestEventsAPIServer_HandleReactionRemovedEvent(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t)
+
+	req := httptest.NewRequest("POST", "/foo", toJSON(slackevents.EventsAPICallbackEvent{
+		Type: slackevents.CallbackEvent,
+		InnerEvent: rawJSON(slackevents.ReactionRemovedEvent{
+			Type:     slackevents.ReactionRemoved,
+			User:     "U1234",
+			Reaction: "+1",
+			Item: slackevents.Item{
+				Type:      "message",
+				Channel:   "D023BB3L2",
+				Timestamp: "1595070350",
+			},
+		}),
+	}))
+
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	events := recordedEvents()
+	require.NotEmpty(t, events)
+	require.IsType(t, ReactionRemovedEvent{}, events[0])
+
+	actual := events[0].(ReactionRemovedEvent)
+	assert.Equal(t, "D023BB3L2", actual.Channel)
+	assert.Equal(t, "1595070350", actual.MessageID)
+	assert.Equal(t, "U1234", actual.AuthorID)
+	assert.Equal(t, "+1", actual.Reaction.Shortcode)
+}
+
 func TestEventsAPIServer_HTTPHandlerMiddleware(t *testing.T) {
 	middleware := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -191,6 +239,281 @@ func TestEventsAPIServer_HTTPHandlerMiddleware(t *testing.T) {
 	assert.Empty(t, errorLogs.All())
 }
 
+func TestEventsAPIServer_SigningSecret(t *testing.T) {
+	const secret = "8f742231b10e8888abcd99yyyzzz85a5"
+	body, err := io.ReadAll(toJSON(slackevents.EventsAPICallbackEvent{
+		Type: slackevents.CallbackEvent,
+		InnerEvent: rawJSON(slackevents.MessageEvent{
+			Type:        slackevents.Message,
+			Channel:     "D023BB3L2",
+			ChannelType: "im",
+			User:        "U1234",
+			Text:        "Hello World!",
+		}),
+	}))
+	require.NoError(t, err)
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	cases := map[string]struct {
+		timestamp, signature string
+		expectedCode         int
+	}{
+		"valid signature":   {now, sign(secret, now, body), http.StatusOK},
+		"invalid signature": {now, sign("wrong-secret", now, body), http.StatusUnauthorized},
+		"replayed request":  {old, sign(secret, old, body), http.StatusUnauthorized},
+		"missing signature": {now, "", http.StatusUnauthorized},
+		"invalid timestamp": {"yesterday", sign(secret, "yesterday", body), http.StatusUnauthorized},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, recordedEvents := newTestEventsAPIServer(t, Config{
+				EventsAPI: EventsAPIConfig{SigningSecret: secret},
+			})
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+			req.Header.Set("X-Slack-Request-Timestamp", c.timestamp)
+			req.Header.Set("X-Slack-Signature", c.signature)
+
+			resp := httptest.NewRecorder()
+			s.httpHandler(resp, req)
+			assert.Equal(t, c.expectedCode, resp.Code)
+
+			events := recordedEvents()
+			if c.expectedCode == http.StatusOK {
+				assert.Len(t, events, 1)
+			} else {
+				assert.Empty(t, events)
+			}
+		})
+	}
+}
+
+func TestEventsAPIServer_QueuePolicy(t *testing.T) {
+	cases := map[QueuePolicy]struct {
+		expectedCode   int
+		expectedEvents int
+	}{
+		QueuePolicyDrop:   {http.StatusOK, 2},
+		QueuePolicyReject: {http.StatusServiceUnavailable, 2},
+	}
+
+	for policy, c := range cases {
+		t.Run(fmt.Sprint(policy), func(t *testing.T) {
+			// The event processing loop is not started yet so the queue fills up.
+			s := newTestEventsAPIServerWithoutBrain(t, Config{
+				EventsAPI: EventsAPIConfig{QueueSize: 1, QueuePolicy: policy},
+			})
+
+			send := func() int {
+				req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+					Type: slackevents.CallbackEvent,
+					InnerEvent: rawJSON(slackevents.MessageEvent{
+						Type:        slackevents.Message,
+						Channel:     "D023BB3L2",
+						ChannelType: "im",
+						Text:        "Hello World!",
+					}),
+				}))
+
+				resp := httptest.NewRecorder()
+				s.httpHandler(resp, req)
+				return resp.Code
+			}
+
+			assert.Equal(t, http.StatusOK, send()) // taken by the queue worker
+			for len(s.queue) > 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			assert.Equal(t, http.StatusOK, send())  // waiting in the queue
+			assert.Equal(t, c.expectedCode, send()) // overflow
+
+			recordedEvents := startTestBrain(t, s)
+			assert.Len(t, recordedEvents(), c.expectedEvents)
+		})
+	}
+}
+
+func TestEventsAPIServer_CloseWithBlockedHandler(t *testing.T) {
+	// The event processing loop is not started yet so the queue fills up.
+	s := newTestEventsAPIServerWithoutBrain(t, Config{
+		EventsAPI: EventsAPIConfig{QueueSize: 1, QueuePolicy: QueuePolicyBlock},
+	})
+
+	send := func(text string) {
+		req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+			Type: slackevents.CallbackEvent,
+			InnerEvent: rawJSON(slackevents.MessageEvent{
+				Type:        slackevents.Message,
+				Channel:     "D023BB3L2",
+				ChannelType: "im",
+				Text:        text,
+			}),
+		}))
+
+		s.httpHandler(httptest.NewRecorder(), req)
+	}
+
+	send("taken by the queue worker")
+	for len(s.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	send("waiting in the queue")
+
+	handlerDone := make(chan bool)
+	go func() {
+		send("waiting for room in the queue")
+		handlerDone <- true
+	}()
+	time.Sleep(10 * time.Millisecond) // let the handler block
+
+	closeErr := make(chan error)
+	go func() { closeErr <- s.Close() }()
+
+	// The blocked handler must give up instead of sending on the closed queue.
+	<-handlerDone
+
+	var texts []string
+	for evt := range s.events {
+		texts = append(texts, evt.Data.(*slack.MessageEvent).Text)
+	}
+
+	assert.NoError(t, <-closeErr)
+	assert.Equal(t, []string{"taken by the queue worker", "waiting in the queue"}, texts)
+
+	// Events that are received after the queue was closed are rejected.
+	req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+		Type:       slackevents.CallbackEvent,
+		InnerEvent: rawJSON(slackevents.MessageEvent{Type: slackevents.Message}),
+	}))
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
+
+func TestEventsAPIServer_CloseWithoutEventProcessing(t *testing.T) {
+	// The event processing loop is never started, so the queued events
+	// cannot be processed.
+	s := newTestEventsAPIServerWithoutBrain(t, Config{
+		EventsAPI: EventsAPIConfig{ShutdownTimeout: 10 * time.Millisecond},
+	})
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+			Type:       slackevents.CallbackEvent,
+			InnerEvent: rawJSON(slackevents.MessageEvent{Type: slackevents.Message}),
+		}))
+		s.httpHandler(httptest.NewRecorder(), req)
+	}
+
+	closed := make(chan error)
+	go func() { closed <- s.Close() }()
+
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	// Closing the server again is a no-op.
+	assert.NoError(t, s.Close())
+}
+
+func TestEventsAPIServer_RejectedEventsAreRetried(t *testing.T) {
+	// The event processing loop is not started yet so the queue fills up.
+	s := newTestEventsAPIServerWithoutBrain(t, Config{
+		EventsAPI: EventsAPIConfig{QueueSize: 1, QueuePolicy: QueuePolicyReject},
+	})
+
+	send := func(eventID string) int {
+		req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+			Type:    slackevents.CallbackEvent,
+			EventID: eventID,
+			InnerEvent: rawJSON(slackevents.MessageEvent{
+				Type:        slackevents.Message,
+				Channel:     "D023BB3L2",
+				ChannelType: "im",
+				Text:        "Hello " + eventID,
+			}),
+		}))
+
+		resp := httptest.NewRecorder()
+		s.httpHandler(resp, req)
+		return resp.Code
+	}
+
+	waitForQueue := func() {
+		for len(s.queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, send("Ev01")) // taken by the queue worker
+	waitForQueue()
+
+	assert.Equal(t, http.StatusOK, send("Ev02"))                 // waiting in the queue
+	assert.Equal(t, http.StatusServiceUnavailable, send("Ev03")) // overflow
+
+	recordedEvents := startTestBrain(t, s)
+	waitForQueue()
+
+	// Slack retries the rejected event, which must not be ignored as duplicate.
+	assert.Equal(t, http.StatusOK, send("Ev03"))
+
+	events := recordedEvents()
+	require.Len(t, events, 3)
+	assert.Equal(t, "Hello Ev03", events[2].(joe.ReceiveMessageEvent).Text)
+}
+
+func TestEventsAPIServer_Deduplication(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t, Config{
+		EventsAPI: EventsAPIConfig{NoRetry: true},
+	})
+
+	send := func(eventID string, retry int) {
+		req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+			Type:    slackevents.CallbackEvent,
+			EventID: eventID,
+			InnerEvent: rawJSON(slackevents.MessageEvent{
+				Type:        slackevents.Message,
+				Channel:     "D023BB3L2",
+				ChannelType: "im",
+				Text:        "Hello " + eventID,
+			}),
+		}))
+
+		if retry > 0 {
+			req.Header.Set("X-Slack-Retry-Num", strconv.Itoa(retry))
+			req.Header.Set("X-Slack-Retry-Reason", "http_timeout")
+		}
+
+		resp := httptest.NewRecorder()
+		s.httpHandler(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "1", resp.Header().Get("X-Slack-No-Retry"))
+	}
+
+	send("Ev01", 0)
+	send("Ev01", 1)
+	send("Ev02", 0)
+	send("Ev01", 2)
+
+	events := recordedEvents()
+	require.Len(t, events, 2)
+	assert.Equal(t, "Hello Ev01", events[0].(joe.ReceiveMessageEvent).Text)
+	assert.Equal(t, "Hello Ev02", events[1].(joe.ReceiveMessageEvent).Text)
+}
+
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
 func toJSON(req interface{}) io.Reader {
 	b := new(bytes.Buffer)
 	err := json.NewEncoder(b).Encode(req)
@@ -210,3 +533,18 @@ func rawJSON(v interface{}) *json.RawMessage {
 	msg := json.RawMessage(raw)
 	return &msg
 }
+
+// parseInnerEvent parses the given inner event of the Events API the same way
+// the EventsAPIServer does.
+func parseInnerEvent(t *testing.T, event interface{}) slackevents.EventsAPIInnerEvent {
+	callback, err := json.Marshal(slackevents.EventsAPICallbackEvent{
+		Type:       slackevents.CallbackEvent,
+		InnerEvent: rawJSON(event),
+	})
+	require.NoError(t, err)
+
+	apiEvent, err := slackevents.ParseEvent(callback, slackevents.OptionNoVerifyToken())
+	require.NoError(t, err)
+
+	return apiEvent.InnerEvent
+}
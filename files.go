@@ -0,0 +1,243 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// sharedFilesWindow is the time in which a file that was shared is not
+// emitted again. Slack sends a file_shared event as well as a message with the
+// file_share subtype when a user uploads a file so we have to ignore one of
+// them.
+const sharedFilesWindow = time.Minute
+
+// The FileSharedEvent is emitted when a user shares a file in a channel that
+// the bot is a member of (e.g. by uploading it). Use the Download(…) function
+// to read the content of the file.
+//
+// See https://api.slack.com/events/file_shared
+type FileSharedEvent struct {
+	File         slack.File
+	UserID       string              // the user who shared the file
+	Channel      string              // the channel in which the file was shared (empty if unknown)
+	Message      *slack.MessageEvent // the message that shared the file (nil if unknown)
+	TeamID       string
+	EnterpriseID string
+
+	download func(downloadURL string, w io.Writer) error
+}
+
+// Download writes the content of the shared file to the given writer. The file
+// is downloaded with the token of the bot, so it must have the "files:read"
+// scope.
+func (e FileSharedEvent) Download(w io.Writer) error {
+	if e.download == nil {
+		return errors.New("file can only be downloaded from events that were emitted by the adapter")
+	}
+
+	downloadURL := e.File.URLPrivateDownload
+	if downloadURL == "" {
+		downloadURL = e.File.URLPrivate
+	}
+
+	err := e.download(downloadURL, w)
+	if err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return nil
+}
+
+// An UploadOption configures files that are uploaded via BotAdapter.UploadFile(…).
+type UploadOption func(*slack.FileUploadParameters)
+
+// UploadInThread shares the uploaded file as reply in the thread with the given
+// timestamp.
+func UploadInThread(threadTS string) UploadOption {
+	return func(params *slack.FileUploadParameters) {
+		params.ThreadTimestamp = threadTS
+	}
+}
+
+// UploadWithComment adds a message that is shared together with the file.
+func UploadWithComment(comment string) UploadOption {
+	return func(params *slack.FileUploadParameters) {
+		params.InitialComment = comment
+	}
+}
+
+// UploadWithTitle sets the title of the uploaded file. By default the filename
+// is used as title.
+func UploadWithTitle(title string) UploadOption {
+	return func(params *slack.FileUploadParameters) {
+		params.Title = title
+	}
+}
+
+// UploadWithFiletype sets the type of the uploaded file (e.g. "csv"). By default
+// Slack detects the type automatically.
+// See https://api.slack.com/types/file#file_types
+func UploadWithFiletype(filetype string) UploadOption {
+	return func(params *slack.FileUploadParameters) {
+		params.Filetype = filetype
+	}
+}
+
+// UploadFile uploads the content of the reader as file with the given name and
+// shares it in the given channel. If the channel contains a thread timestamp
+// (see WithThreadedResponses), the file is shared in this thread.
+func (a *BotAdapter) UploadFile(channelID, filename string, r io.Reader, opts ...UploadOption) (*slack.File, error) {
+	params := slack.FileUploadParameters{
+		Reader:   r,
+		Filename: filename,
+	}
+
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	file, err := a.uploadFile(channelID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return file, nil
+}
+
+// uploadFile shares a file with the given parameters in the channel.
+func (a *BotAdapter) uploadFile(channelID string, params slack.FileUploadParameters) (*slack.File, error) {
+	channelID, threadTS := splitThreadChannel(channelID)
+	channelID, err := a.resolveChannel(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.ThreadTimestamp == "" {
+		params.ThreadTimestamp = threadTS
+	}
+
+	params.Channels = []string{channelID}
+	a.logger.Info("Uploading file to channel",
+		zap.String("channel_id", channelID),
+		zap.String("filename", params.Filename),
+	)
+
+	return a.slack.UploadFileContext(a.context, params)
+}
+
+// See https://api.slack.com/events/message/file_share
+func (a *BotAdapter) handleFileShareMessage(ev *slack.MessageEvent, brain joe.EventEmitter) {
+	channel := ev.Channel
+	if ev.ThreadTimestamp != "" {
+		channel = threadChannel(ev.Channel, ev.ThreadTimestamp)
+	}
+
+	for _, file := range ev.Files {
+		if a.sharedFiles.seen(file.ID) {
+			continue
+		}
+
+		brain.Emit(FileSharedEvent{
+			File:         file,
+			UserID:       ev.User,
+			Channel:      channel,
+			Message:      ev,
+			TeamID:       a.teamID,
+			EnterpriseID: a.enterpriseID,
+			download:     a.slack.GetFile,
+		})
+	}
+}
+
+// See https://api.slack.com/events/file_shared
+func (a *BotAdapter) handleFileSharedEvent(ev *slack.FileSharedEvent, brain joe.EventEmitter) {
+	fileID := ev.FileID
+	if fileID == "" {
+		fileID = ev.File.ID
+	}
+
+	if a.sharedFiles.seen(fileID) {
+		return
+	}
+
+	// The event only contains the ID of the file.
+	file, _, _, err := a.slack.GetFileInfoContext(a.context, fileID, 0, 0)
+	if err != nil {
+		a.logger.Error("Failed to get info of shared file",
+			zap.String("file_id", fileID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if file.User == a.userID {
+		// file is from us, ignore it!
+		return
+	}
+
+	// The event does not tell us in which channel the file was shared. If the
+	// file was only shared in a single channel so far, it must be this one.
+	var channel string
+	var channels []string
+	channels = append(channels, file.Channels...)
+	channels = append(channels, file.Groups...)
+	channels = append(channels, file.IMs...)
+	if len(channels) == 1 {
+		channel = channels[0]
+	}
+
+	brain.Emit(FileSharedEvent{
+		File:         *file,
+		UserID:       file.User,
+		Channel:      channel,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		download:     a.slack.GetFile,
+	})
+}
+
+// sharedFiles remembers the IDs of recently shared files so each file is only
+// emitted once, no matter if we see the file_shared event or the message first.
+type sharedFiles struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	files map[string]time.Time
+}
+
+func newSharedFiles(window time.Duration) *sharedFiles {
+	return &sharedFiles{
+		window: window,
+		now:    time.Now,
+		files:  map[string]time.Time{},
+	}
+}
+
+// seen returns true if the file with the given ID was shared within the time
+// window. Otherwise it remembers the file and returns false.
+func (s *sharedFiles) seen(fileID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, t := range s.files {
+		if now.Sub(t) > s.window {
+			delete(s.files, id)
+		}
+	}
+
+	if _, ok := s.files[fileID]; ok {
+		return true
+	}
+
+	s.files[fileID] = now
+	return false
+}
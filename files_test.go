@@ -0,0 +1,221 @@
+package slack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdapter_UploadFile(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	r := strings.NewReader("a,b,c")
+	slackAPI.On("UploadFileContext", a.context, slack.FileUploadParameters{
+		Reader:          r,
+		Filename:        "report.csv",
+		Filetype:        "csv",
+		Title:           "Report",
+		InitialComment:  "Here is your report",
+		Channels:        []string{"C1H9RESGL"},
+		ThreadTimestamp: "1360782400.498405",
+	}).Return(&slack.File{ID: "F1"}, nil)
+
+	file, err := a.UploadFile("C1H9RESGL", "report.csv", r,
+		UploadInThread("1360782400.498405"),
+		UploadWithComment("Here is your report"),
+		UploadWithTitle("Report"),
+		UploadWithFiletype("csv"),
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, "F1", file.ID)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_UploadFileInThreadChannel(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	r := strings.NewReader("Hello World")
+	slackAPI.On("UploadFileContext", a.context, slack.FileUploadParameters{
+		Reader:          r,
+		Filename:        "hello.txt",
+		Channels:        []string{"C1H9RESGL"},
+		ThreadTimestamp: "1360782400.498405",
+	}).Return(&slack.File{ID: "F1"}, nil)
+
+	_, err := a.UploadFile("C1H9RESGL/1360782400.498405", "hello.txt", r)
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_UploadFileError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("UploadFileContext", a.context, mock.Anything).
+		Return(nil, errors.New("not_in_channel"))
+
+	_, err := a.UploadFile("C1H9RESGL", "hello.txt", strings.NewReader("Hello World"))
+	assert.EqualError(t, err, "failed to upload file: not_in_channel")
+}
+
+func TestAdapter_FileShareMessage(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	file := slack.File{
+		ID:                 "F1",
+		Name:               "report.csv",
+		User:               "U1234",
+		URLPrivateDownload: "https://files.slack.com/files-pri/T1-F1/download/report.csv",
+	}
+
+	ev := &slack.MessageEvent{
+		Msg: slack.Msg{
+			SubType:         "file_share",
+			Channel:         "C1H9RESGL",
+			User:            "U1234",
+			Timestamp:       "1360782500.498405",
+			ThreadTimestamp: "1360782400.498405",
+			Upload:          true,
+			Files:           []slack.File{file},
+		},
+	}
+
+	expectConversation(slackAPI, a.context, "C1H9RESGL", false)
+	events := processTestEvents(t, a, ev, &slack.FileSharedEvent{FileID: "F1"})
+	require.Len(t, events, 1, "the file must only be emitted once")
+
+	actual, ok := events[0].(FileSharedEvent)
+	require.True(t, ok)
+	assert.Equal(t, file, actual.File)
+	assert.Equal(t, "U1234", actual.UserID)
+	assert.Equal(t, "C1H9RESGL/1360782400.498405", actual.Channel)
+	assert.Equal(t, ev, actual.Message)
+
+	var buf bytes.Buffer
+	slackAPI.On("GetFile", file.URLPrivateDownload, &buf).
+		Run(func(args mock.Arguments) {
+			_, _ = io.WriteString(args.Get(1).(io.Writer), "a,b,c")
+		}).
+		Return(nil)
+
+	require.NoError(t, actual.Download(&buf))
+	assert.Equal(t, "a,b,c", buf.String())
+}
+
+func TestAdapter_FileSharedEvent(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	file := &slack.File{
+		ID:         "F1",
+		Name:       "report.csv",
+		User:       "U1234",
+		URLPrivate: "https://files.slack.com/files-pri/T1-F1/report.csv",
+		Channels:   []string{"C1H9RESGL"},
+	}
+
+	slackAPI.On("GetFileInfoContext", a.context, "F1", 0, 0).Return(file, nil)
+	slackAPI.On("GetFile", file.URLPrivate, mock.Anything).Return(errors.New("404 Not Found"))
+
+	events := processTestEvents(t, a, &slack.FileSharedEvent{FileID: "F1"})
+	require.Len(t, events, 1)
+
+	actual, ok := events[0].(FileSharedEvent)
+	require.True(t, ok)
+	assert.Equal(t, *file, actual.File)
+	assert.Equal(t, "U1234", actual.UserID)
+	assert.Equal(t, "C1H9RESGL", actual.Channel)
+	assert.Nil(t, actual.Message)
+
+	err := actual.Download(new(bytes.Buffer))
+	assert.EqualError(t, err, "failed to download file: 404 Not Found")
+}
+
+func TestAdapter_FileSharedEventFromBot(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	file := &slack.File{ID: "F1", User: a.userID}
+	slackAPI.On("GetFileInfoContext", a.context, "F1", 0, 0).Return(file, nil)
+
+	events := processTestEvents(t, a, &slack.FileSharedEvent{FileID: "F1"})
+	assert.Empty(t, events)
+}
+
+func TestAdapter_FileSharedEventError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	slackAPI.On("GetFileInfoContext", a.context, "F1", 0, 0).Return(nil, errors.New("file_not_found"))
+
+	events := processTestEvents(t, a, &slack.FileSharedEvent{FileID: "F1"})
+	assert.Empty(t, events)
+}
+
+func TestFileSharedEvent_DownloadWithoutAdapter(t *testing.T) {
+	ev := FileSharedEvent{File: slack.File{ID: "F1"}}
+	err := ev.Download(new(bytes.Buffer))
+	assert.EqualError(t, err, "file can only be downloaded from events that were emitted by the adapter")
+}
+
+func TestEventsAPIEvent_Files(t *testing.T) {
+	a, _ := newTestAdapter(t)
+
+	evt, ok := a.eventsAPIEvent(slackevents.EventsAPIInnerEvent{
+		Type: "message",
+		Data: &slackevents.MessageEvent{
+			Type:    "message",
+			SubType: "file_share",
+			Channel: "C1H9RESGL",
+			User:    "U1234",
+			Upload:  true,
+			Files: []slackevents.File{{
+				ID:                 "F1",
+				Name:               "report.csv",
+				Size:               42,
+				URLPrivateDownload: "https://files.slack.com/files-pri/T1-F1/download/report.csv",
+			}},
+		},
+	})
+
+	require.True(t, ok)
+	msg := evt.Data.(*slack.MessageEvent)
+	assert.True(t, msg.Upload)
+	assert.Equal(t, []slack.File{{
+		ID:                 "F1",
+		Name:               "report.csv",
+		Size:               42,
+		URLPrivateDownload: "https://files.slack.com/files-pri/T1-F1/download/report.csv",
+	}}, msg.Files)
+
+	// The file_shared event is parsed as RTM event type.
+	evt, ok = a.eventsAPIEvent(parseInnerEvent(t, map[string]interface{}{
+		"type":       "file_shared",
+		"file_id":    "F1",
+		"user_id":    "U1234",
+		"channel_id": "C1H9RESGL",
+		"file":       map[string]string{"id": "F1"},
+	}))
+	require.True(t, ok)
+	assert.Equal(t, "F1", evt.Data.(*slack.FileSharedEvent).FileID)
+}
+
+func TestSharedFiles(t *testing.T) {
+	now := time.Now()
+	s := newSharedFiles(time.Minute)
+	s.now = func() time.Time { return now }
+
+	assert.False(t, s.seen("F1"))
+	assert.True(t, s.seen("F1"))
+	assert.False(t, s.seen("F2"))
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, s.seen("F1"), "files should be forgotten after the window")
+	assert.Len(t, s.files, 1)
+}
@@ -0,0 +1,131 @@
+package slack
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// eventFilter decides which messages and reactions are passed to the brain
+// based on the channel they were sent in and on their author.
+type eventFilter struct {
+	allowedChannels   map[string]bool // channel IDs and names without "#"
+	deniedChannels    map[string]bool // channel IDs and names without "#"
+	allowedUsers      map[string]bool // user IDs and names without "@"
+	denyExternalUsers bool
+}
+
+// newEventFilter returns the filter of the given configuration or nil if
+// all events are allowed.
+func newEventFilter(conf FilterConfig) *eventFilter {
+	if len(conf.AllowedChannels) == 0 && len(conf.DeniedChannels) == 0 &&
+		len(conf.AllowedUsers) == 0 && !conf.DenyExternalUsers {
+		return nil
+	}
+
+	return &eventFilter{
+		allowedChannels:   filterSet(conf.AllowedChannels, "#"),
+		deniedChannels:    filterSet(conf.DeniedChannels, "#"),
+		allowedUsers:      filterSet(conf.AllowedUsers, "@"),
+		denyExternalUsers: conf.DenyExternalUsers,
+	}
+}
+
+func filterSet(values []string, prefix string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.TrimPrefix(v, prefix)] = true
+	}
+
+	return set
+}
+
+// allowEvent returns true if an event of the given user in the given channel
+// may be passed to the brain. Dropped events are logged with the reason.
+func (a *BotAdapter) allowEvent(channelID, userID string) bool {
+	if a.filter == nil {
+		return true
+	}
+
+	reason := a.filterReason(channelID, userID)
+	if reason == "" {
+		return true
+	}
+
+	a.logger.Debug("Ignoring event",
+		zap.String("reason", reason),
+		zap.String("channel_id", channelID),
+		zap.String("user_id", userID),
+	)
+	return false
+}
+
+// filterReason returns why an event of the given user in the given channel
+// must be dropped or an empty string if it is allowed. If the channel or user
+// cannot be looked up to match them by name, the event is dropped.
+func (a *BotAdapter) filterReason(channelID, userID string) string {
+	f := a.filter
+
+	if len(f.allowedChannels) > 0 || len(f.deniedChannels) > 0 {
+		name, err := a.channelName(channelID)
+		if err != nil {
+			return "failed to look up channel: " + err.Error()
+		}
+
+		if f.deniedChannels[channelID] || (name != "" && f.deniedChannels[name]) {
+			return "channel is denied"
+		}
+
+		if len(f.allowedChannels) > 0 && !f.allowedChannels[channelID] && (name == "" || !f.allowedChannels[name]) {
+			return "channel is not allowed"
+		}
+	}
+
+	if len(f.allowedUsers) == 0 && !f.denyExternalUsers {
+		return ""
+	}
+
+	if userID == "" {
+		// e.g. messages of integrations that do not have a user
+		if len(f.allowedUsers) > 0 {
+			return "user is not allowed"
+		}
+		return ""
+	}
+
+	if f.allowedUsers[userID] && !f.denyExternalUsers {
+		return ""
+	}
+
+	user, err := a.slackUser(userID)
+	if err != nil {
+		return "failed to look up user: " + err.Error()
+	}
+
+	if len(f.allowedUsers) > 0 && !f.allowedUsers[userID] && !f.allowedUsers[user.Name] {
+		return "user is not allowed"
+	}
+
+	if f.denyExternalUsers && a.isExternalUser(user) {
+		return "user is external"
+	}
+
+	return ""
+}
+
+// channelName returns the name of the channel with the given ID. It does not
+// look up the channel if it is already matched by its ID. Direct messages do
+// not have a name.
+func (a *BotAdapter) channelName(channelID string) (string, error) {
+	f := a.filter
+	if f.deniedChannels[channelID] || f.allowedChannels[channelID] {
+		return "", nil
+	}
+
+	channel, err := a.slackChannel(channelID)
+	if err != nil {
+		return "", err
+	}
+
+	return channel.Name, nil
+}
@@ -0,0 +1,173 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-joe/joe"
+	"github.com/go-joe/joe/reactions"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFilterTestMessage(channel, user string) *slack.MessageEvent {
+	return &slack.MessageEvent{Msg: slack.Msg{
+		Channel:   channel,
+		User:      user,
+		Text:      "<@42> hello",
+		Timestamp: "1360782400.498405",
+	}}
+}
+
+func newFilterTestEdit(channel, user string) *slack.MessageEvent {
+	return &slack.MessageEvent{
+		Msg: slack.Msg{
+			SubType:   "message_changed",
+			Channel:   channel,
+			Timestamp: "1360782500.498405",
+		},
+		SubMessage:      &slack.Msg{User: user, Text: "new", Timestamp: "1360782400.498405"},
+		PreviousMessage: &slack.Msg{User: user, Text: "old", Timestamp: "1360782400.498405"},
+	}
+}
+
+func newFilterTestDelete(channel, user string) *slack.MessageEvent {
+	return &slack.MessageEvent{
+		Msg: slack.Msg{
+			SubType:          "message_deleted",
+			Channel:          channel,
+			Timestamp:        "1360782500.498405",
+			DeletedTimestamp: "1360782400.498405",
+		},
+		PreviousMessage: &slack.Msg{User: user, Text: "old", Timestamp: "1360782400.498405"},
+	}
+}
+
+func TestNewEventFilter(t *testing.T) {
+	assert.Nil(t, newEventFilter(FilterConfig{}))
+
+	f := newEventFilter(FilterConfig{
+		AllowedChannels: []string{"#general", "C1"},
+		AllowedUsers:    []string{"@jd", "U1"},
+	})
+	require.NotNil(t, f)
+	assert.Equal(t, map[string]bool{"general": true, "C1": true}, f.allowedChannels)
+	assert.Equal(t, map[string]bool{"jd": true, "U1": true}, f.allowedUsers)
+	assert.Empty(t, f.deniedChannels)
+}
+
+func TestAdapter_ChannelFilter(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.filter = newEventFilter(FilterConfig{
+		AllowedChannels: []string{"C1", "#general", "#random"},
+		DeniedChannels:  []string{"#random"},
+	})
+
+	newChannel := func(id, name string) *slack.Channel {
+		channel := &slack.Channel{}
+		channel.ID = id
+		channel.Name = name
+		return channel
+	}
+
+	slackAPI.On("GetConversationInfoContext", a.context, "C2", false).Return(newChannel("C2", "general"), nil).Once()
+	slackAPI.On("GetConversationInfoContext", a.context, "C3", false).Return(newChannel("C3", "random"), nil).Once()
+	slackAPI.On("GetConversationInfoContext", a.context, "C4", false).Return(newChannel("C4", "deployments"), nil).Once()
+	slackAPI.On("GetConversationInfoContext", a.context, "C5", false).Return(nil, errors.New("channel_not_found")).Once()
+
+	reaction := &slack.ReactionAddedEvent{User: "U1", Reaction: "+1"}
+	reaction.Item.Type = "message"
+	reaction.Item.Channel = "C4"
+	reaction.Item.Timestamp = "1360782400.498405"
+
+	removedReaction := &slack.ReactionRemovedEvent{User: "U1", Reaction: "+1"}
+	removedReaction.Item = reaction.Item
+	removedReaction.Item.Channel = "C3"
+
+	events := processTestEvents(t, a,
+		newFilterTestMessage("C1", "U1"), // allowed by ID
+		newFilterTestMessage("C2", "U1"), // allowed by name
+		newFilterTestMessage("C3", "U1"), // denied by name
+		newFilterTestMessage("C4", "U1"), // not allowed
+		newFilterTestMessage("C5", "U1"), // unknown
+		reaction,                         // not allowed
+		removedReaction,                  // denied by name
+		newFilterTestEdit("C3", "U1"),    // denied by name
+		newFilterTestDelete("C4", "U1"),  // not allowed
+		newFilterTestEdit("C1", "U1"),    // allowed by ID
+		newFilterTestDelete("C2", "U1"),  // allowed by name
+	)
+
+	require.Len(t, events, 4)
+	assert.Equal(t, "C1", events[0].(joe.ReceiveMessageEvent).Channel)
+	assert.Equal(t, "C2", events[1].(joe.ReceiveMessageEvent).Channel)
+	assert.Equal(t, "C1", events[2].(MessageEditedEvent).Channel)
+	assert.Equal(t, "C2", events[3].(MessageDeletedEvent).Channel)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_UserFilter(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.teamID = "T1"
+	a.filter = newEventFilter(FilterConfig{
+		AllowedUsers:      []string{"U1", "@jd", "@external"},
+		DenyExternalUsers: true,
+	})
+
+	slackAPI.On("GetUserInfoContext", a.context, "U1").Return(&slack.User{ID: "U1", Name: "admin", TeamID: "T1"}, nil).Once()
+	slackAPI.On("GetUserInfoContext", a.context, "U2").Return(&slack.User{ID: "U2", Name: "jd", TeamID: "T1"}, nil).Once()
+	slackAPI.On("GetUserInfoContext", a.context, "U3").Return(&slack.User{ID: "U3", Name: "other", TeamID: "T1"}, nil).Once()
+	slackAPI.On("GetUserInfoContext", a.context, "U4").Return(&slack.User{ID: "U4", Name: "external", TeamID: "T2"}, nil).Once()
+
+	reaction := &slack.ReactionAddedEvent{User: "U2", Reaction: "+1"}
+	reaction.Item.Type = "message"
+	reaction.Item.Channel = "C1"
+	reaction.Item.Timestamp = "1360782400.498405"
+
+	removedReaction := &slack.ReactionRemovedEvent{User: "U4", Reaction: "+1"}
+	removedReaction.Item = reaction.Item
+
+	events := processTestEvents(t, a,
+		newFilterTestMessage("C1", "U1"), // allowed by ID
+		newFilterTestMessage("C1", "U2"), // allowed by name
+		newFilterTestMessage("C1", "U3"), // not allowed
+		newFilterTestMessage("C1", "U4"), // external
+		reaction,                         // allowed by name
+		removedReaction,                  // external
+		newFilterTestEdit("C1", "U3"),    // not allowed
+		newFilterTestDelete("C1", "U4"),  // external
+		newFilterTestEdit("C1", "U2"),    // allowed by name
+		newFilterTestDelete("C1", "U1"),  // allowed by ID
+	)
+
+	require.Len(t, events, 5)
+	assert.Equal(t, "U1", events[0].(joe.ReceiveMessageEvent).AuthorID)
+	assert.Equal(t, "U2", events[1].(joe.ReceiveMessageEvent).AuthorID)
+	assert.Equal(t, reactions.Event{
+		Channel:   "C1",
+		MessageID: "1360782400.498405",
+		AuthorID:  "U2",
+		Reaction:  reactions.Reaction{Shortcode: "+1"},
+	}, events[2])
+	assert.Equal(t, "U2", events[3].(MessageEditedEvent).AuthorID)
+	assert.Equal(t, "U1", events[4].(MessageDeletedEvent).AuthorID)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_DenyExternalUsers(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.filter = newEventFilter(FilterConfig{DenyExternalUsers: true})
+
+	slackAPI.On("GetUserInfoContext", a.context, "U1").Return(nil, errors.New(errUserNotFound)).Once()
+	slackAPI.On("GetUserInfoContext", a.context, "U2").Return(&slack.User{ID: "U2"}, nil).Once()
+
+	events := processTestEvents(t, a,
+		newFilterTestMessage("C1", "U1"),
+		newFilterTestMessage("C1", "U2"),
+	)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "U2", events[0].(joe.ReceiveMessageEvent).AuthorID)
+	slackAPI.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/go-joe/joe v0.9.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/slack-go/slack v0.6.5
 	github.com/stretchr/testify v1.3.0
@@ -0,0 +1,146 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-joe/joe"
+)
+
+// ErrInstallationNotFound is returned by an InstallationStore if the app is not
+// installed in the requested workspace.
+var ErrInstallationNotFound = errors.New("installation not found")
+
+// An Installation contains the credentials of the app in a single workspace
+// (or in all workspaces of an Enterprise Grid organization if TeamID is empty).
+type Installation struct {
+	TeamID         string    `json:"team_id"`
+	TeamName       string    `json:"team_name"`
+	EnterpriseID   string    `json:"enterprise_id,omitempty"`
+	EnterpriseName string    `json:"enterprise_name,omitempty"`
+	BotToken       string    `json:"bot_token"`
+	BotUserID      string    `json:"bot_user_id"`
+	Scope          string    `json:"scope"`
+	InstalledAt    time.Time `json:"installed_at"`
+}
+
+// An InstallationStore persists the installations of a Slack app that is
+// distributed to multiple workspaces.
+type InstallationStore interface {
+	// Save stores the installation, replacing any previous installation for
+	// the same team and enterprise.
+	Save(inst Installation) error
+
+	// Find returns the installation for the given team and enterprise ID. If
+	// the app was installed for a whole Enterprise Grid organization, the team
+	// ID of the installation is empty. If there is no matching installation,
+	// the ErrInstallationNotFound error is returned.
+	Find(teamID, enterpriseID string) (*Installation, error)
+}
+
+// installationKey returns the key under which the installation for the given
+// team and enterprise ID is stored.
+func installationKey(teamID, enterpriseID string) string {
+	return enterpriseID + "/" + teamID
+}
+
+// findInstallation looks up the installation of a team and falls back to an
+// organization wide installation of the enterprise.
+func findInstallation(teamID, enterpriseID string, get func(key string) (*Installation, error)) (*Installation, error) {
+	inst, err := get(installationKey(teamID, enterpriseID))
+	if err == nil && inst != nil {
+		return inst, nil
+	}
+	if err != nil && !errors.Is(err, ErrInstallationNotFound) {
+		return nil, err
+	}
+
+	if enterpriseID != "" && teamID != "" {
+		return findInstallation("", enterpriseID, get)
+	}
+
+	return nil, ErrInstallationNotFound
+}
+
+type memoryInstallationStore struct {
+	mu            sync.RWMutex
+	installations map[string]Installation
+}
+
+// NewMemoryInstallationStore returns an InstallationStore that keeps all
+// installations in memory. Installations are lost when the bot restarts, so
+// this store is mostly useful for testing.
+func NewMemoryInstallationStore() InstallationStore {
+	return &memoryInstallationStore{
+		installations: map[string]Installation{},
+	}
+}
+
+func (s *memoryInstallationStore) Save(inst Installation) error {
+	s.mu.Lock()
+	s.installations[installationKey(inst.TeamID, inst.EnterpriseID)] = inst
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryInstallationStore) Find(teamID, enterpriseID string) (*Installation, error) {
+	return findInstallation(teamID, enterpriseID, func(key string) (*Installation, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		inst, ok := s.installations[key]
+		if !ok {
+			return nil, ErrInstallationNotFound
+		}
+
+		return &inst, nil
+	})
+}
+
+// joeMemoryInstallationStore is an InstallationStore that keeps all
+// installations in a joe.Memory.
+type joeMemoryInstallationStore struct {
+	memory joe.Memory
+}
+
+const installationKeyPrefix = "slack.installation."
+
+// NewJoeMemoryInstallationStore returns an InstallationStore that keeps all
+// installations in the given joe.Memory (e.g. a Redis or bolt memory module).
+// Note that the installations contain the bot tokens of all workspaces, so the
+// memory should be protected accordingly.
+func NewJoeMemoryInstallationStore(memory joe.Memory) InstallationStore {
+	return &joeMemoryInstallationStore{memory: memory}
+}
+
+func (s *joeMemoryInstallationStore) Save(inst Installation) error {
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+
+	return s.memory.Set(installationKeyPrefix+installationKey(inst.TeamID, inst.EnterpriseID), data)
+}
+
+func (s *joeMemoryInstallationStore) Find(teamID, enterpriseID string) (*Installation, error) {
+	return findInstallation(teamID, enterpriseID, func(key string) (*Installation, error) {
+		data, ok, err := s.memory.Get(installationKeyPrefix + key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrInstallationNotFound
+		}
+
+		var inst Installation
+		err = json.Unmarshal(data, &inst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode installation %q: %w", key, err)
+		}
+
+		return &inst, nil
+	})
+}
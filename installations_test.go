@@ -0,0 +1,63 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallationStores(t *testing.T) {
+	stores := map[string]InstallationStore{
+		"memory":     NewMemoryInstallationStore(),
+		"joe memory": NewJoeMemoryInstallationStore(newTestMemory()),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			installedAt := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+			team := Installation{
+				TeamID:      "T1",
+				TeamName:    "Team 1",
+				BotToken:    "xoxb-1",
+				BotUserID:   "U1",
+				InstalledAt: installedAt,
+			}
+			org := Installation{
+				EnterpriseID: "E1",
+				BotToken:     "xoxb-org",
+				BotUserID:    "U2",
+				InstalledAt:  installedAt,
+			}
+
+			require.NoError(t, store.Save(team))
+			require.NoError(t, store.Save(org))
+
+			inst, err := store.Find("T1", "")
+			require.NoError(t, err)
+			assert.Equal(t, team, *inst)
+
+			// Workspaces of an enterprise fall back to the organization wide
+			// installation.
+			inst, err = store.Find("T2", "E1")
+			require.NoError(t, err)
+			assert.Equal(t, org, *inst)
+
+			_, err = store.Find("T2", "")
+			assert.True(t, errors.Is(err, ErrInstallationNotFound))
+
+			_, err = store.Find("T1", "E2")
+			assert.True(t, errors.Is(err, ErrInstallationNotFound))
+
+			// Installing the app again replaces the old installation.
+			team.BotToken = "xoxb-new"
+			require.NoError(t, store.Save(team))
+
+			inst, err = store.Find("T1", "")
+			require.NoError(t, err)
+			assert.Equal(t, "xoxb-new", inst.BotToken)
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// interactionResponseTimeout is the time we wait for joe handlers to respond
+// to a view submission. Slack requires a response within three seconds.
+const interactionResponseTimeout = 2500 * time.Millisecond
+
+// The BlockActionEvent is emitted when a user interacts with a Block Kit
+// element (e.g. clicks a button or selects an option of a select menu).
+type BlockActionEvent struct {
+	Actions      []*slack.BlockAction
+	UserID       string
+	ChannelID    string // empty if the action happened in a modal
+	MessageID    string // timestamp of the message that contains the element
+	TriggerID    string // can be used to open a modal
+	ResponseURL  string
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.InteractionCallback
+}
+
+// The ViewSubmissionEvent is emitted when a user submits a modal. Handlers
+// can use the Respond(…) or RespondWithErrors(…) functions to synchronously
+// tell Slack how to proceed with the modal (e.g. to display validation errors).
+type ViewSubmissionEvent struct {
+	CallbackID   string
+	UserID       string
+	TriggerID    string
+	View         slack.View
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.InteractionCallback
+
+	response *interactionResponse
+}
+
+// The ViewClosedEvent is emitted when a user closes a modal that was opened
+// with "notify_on_close" set to true.
+type ViewClosedEvent struct {
+	CallbackID   string
+	UserID       string
+	View         slack.View
+	IsCleared    bool
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.InteractionCallback
+}
+
+// The ShortcutEvent is emitted when a user triggers a global shortcut.
+type ShortcutEvent struct {
+	CallbackID   string
+	UserID       string
+	TriggerID    string
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.InteractionCallback
+}
+
+// The MessageActionEvent is emitted when a user triggers a message shortcut.
+type MessageActionEvent struct {
+	CallbackID   string
+	UserID       string
+	ChannelID    string
+	MessageID    string
+	Message      slack.Message
+	TriggerID    string
+	ResponseURL  string
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.InteractionCallback
+}
+
+// Respond sets the response action of the view submission (e.g. to update the
+// modal or push a new one). This function must be called by the handler before
+// it returns. If it is called multiple times, the last response wins.
+func (e ViewSubmissionEvent) Respond(resp *slack.ViewSubmissionResponse) {
+	if e.response != nil {
+		e.response.set(resp)
+	}
+}
+
+// RespondWithErrors lets the modal display the given validation errors. The
+// map keys are the block IDs of the input blocks that contain invalid values.
+func (e ViewSubmissionEvent) RespondWithErrors(errs map[string]string) {
+	e.Respond(slack.NewErrorsViewSubmissionResponse(errs))
+}
+
+// interactionEvent is passed via the slackEvent channel to the event processing
+// loop. It carries the response that handlers may set synchronously.
+type interactionEvent struct {
+	callback *slack.InteractionCallback
+	response *interactionResponse
+}
+
+// interactionResponse is used to pass a response payload from the joe handlers
+// back to the goroutine that acknowledges the interaction.
+type interactionResponse struct {
+	mu      sync.Mutex
+	payload interface{}
+	done    chan struct{}
+}
+
+func newInteractionResponse() *interactionResponse {
+	return &interactionResponse{done: make(chan struct{})}
+}
+
+func (r *interactionResponse) set(payload interface{}) {
+	r.mu.Lock()
+	r.payload = payload
+	r.mu.Unlock()
+}
+
+// finish is called when all joe handlers have processed the event.
+func (r *interactionResponse) finish() {
+	close(r.done)
+}
+
+// wait blocks until all handlers have processed the event or the timeout is
+// reached. It returns the response payload or nil if there is none.
+func (r *interactionResponse) wait(timeout time.Duration) interface{} {
+	select {
+	case <-r.done:
+	case <-time.After(timeout):
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.payload
+}
+
+// newInteractionEvent creates the slackEvent for the given interaction
+// callback. Only view submissions can be answered synchronously so only those
+// carry a response.
+func newInteractionEvent(callback *slack.InteractionCallback) (slackEvent, *interactionResponse) {
+	var resp *interactionResponse
+	if callback.Type == slack.InteractionTypeViewSubmission {
+		resp = newInteractionResponse()
+	}
+
+	return slackEvent{
+		Type:   string(callback.Type),
+		Data:   &interactionEvent{callback: callback, response: resp},
+		TeamID: callback.Team.ID,
+	}, resp
+}
+
+// handleInteraction emits the typed joe event for the interaction.
+func (a *BotAdapter) handleInteraction(ev *interactionEvent, brain joe.EventEmitter) {
+	cb := ev.callback
+	switch cb.Type {
+	case slack.InteractionTypeBlockActions:
+		brain.Emit(BlockActionEvent{
+			Actions:      cb.ActionCallback.BlockActions,
+			UserID:       cb.User.ID,
+			ChannelID:    cb.Channel.ID,
+			MessageID:    cb.Container.MessageTs,
+			TriggerID:    cb.TriggerID,
+			ResponseURL:  cb.ResponseURL,
+			TeamID:       a.teamID,
+			EnterpriseID: a.enterpriseID,
+			Data:         cb,
+		})
+
+	case slack.InteractionTypeViewSubmission:
+		brain.Emit(ViewSubmissionEvent{
+			CallbackID:   cb.View.CallbackID,
+			UserID:       cb.User.ID,
+			TriggerID:    cb.TriggerID,
+			View:         cb.View,
+			TeamID:       a.teamID,
+			EnterpriseID: a.enterpriseID,
+			Data:         cb,
+			response:     ev.response,
+		}, func(joe.Event) {
+			ev.response.finish()
+		})
+
+	case slack.InteractionTypeViewClosed:
+		brain.Emit(ViewClosedEvent{
+			CallbackID:   cb.View.CallbackID,
+			UserID:       cb.User.ID,
+			View:         cb.View,
+			IsCleared:    cb.IsCleared,
+			TeamID:       a.teamID,
+			EnterpriseID: a.enterpriseID,
+			Data:         cb,
+		})
+
+	case slack.InteractionTypeShortcut:
+		brain.Emit(ShortcutEvent{
+			CallbackID:   cb.CallbackID,
+			UserID:       cb.User.ID,
+			TriggerID:    cb.TriggerID,
+			TeamID:       a.teamID,
+			EnterpriseID: a.enterpriseID,
+			Data:         cb,
+		})
+
+	case slack.InteractionTypeMessageAction:
+		brain.Emit(MessageActionEvent{
+			CallbackID:   cb.CallbackID,
+			UserID:       cb.User.ID,
+			ChannelID:    cb.Channel.ID,
+			MessageID:    cb.MessageTs,
+			Message:      cb.Message,
+			TriggerID:    cb.TriggerID,
+			ResponseURL:  cb.ResponseURL,
+			TeamID:       a.teamID,
+			EnterpriseID: a.enterpriseID,
+			Data:         cb,
+		})
+
+	default:
+		a.sampler.sampleUnknown(string(cb.Type), cb)
+		if a.logUnknownMessageTypes {
+			a.logger.Error("Received unknown interaction type",
+				zap.String("type", string(cb.Type)),
+			)
+		}
+	}
+}
+
+// interactionsHandler handles the HTTP requests that Slack sends when users
+// interact with interactive components.
+// See https://api.slack.com/interactivity/handling#payloads
+func (a *EventsAPIServer) interactionsHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := a.readRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var callback slack.InteractionCallback
+	err := parseInteractionCallback(body, &callback)
+	if err != nil {
+		a.logger.Error("Failed to parse interaction payload", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !a.verifyToken(callback.Token) {
+		a.logger.Warn("Rejected interaction with invalid verification token")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// View submissions are answered via the HTTP response, which must not be
+	// written while we wait for room in the event queue.
+	evt, resp := newInteractionEvent(&callback)
+	queued, _ := a.tryEnqueue(evt, w, resp == nil)
+	if !queued || resp == nil {
+		return
+	}
+
+	payload := resp.wait(interactionResponseTimeout)
+	if payload == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(payload)
+	if err != nil {
+		a.logger.Error("Failed to write interaction response", zap.Error(err))
+	}
+}
+
+// parseInteractionCallback parses the form encoded body of an interaction
+// request which contains the JSON payload in its "payload" field.
+func parseInteractionCallback(body []byte, callback *slack.InteractionCallback) error {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(form.Get("payload")), callback)
+}
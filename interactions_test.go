@@ -0,0 +1,202 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-joe/joe/joetest"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func interactionRequest(t *testing.T, path string, callback slack.InteractionCallback) *http.Request {
+	payload, err := json.Marshal(callback)
+	require.NoError(t, err)
+
+	form := url.Values{"payload": {string(payload)}}
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+func TestEventsAPIServer_BlockActions(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t, Config{
+		EventsAPI: EventsAPIConfig{InteractionsPath: "/slack/interactions"},
+	})
+
+	callback := slack.InteractionCallback{
+		Type:        slack.InteractionTypeBlockActions,
+		TriggerID:   "13345224609.738474920.8088930838d88f008e0",
+		ResponseURL: "https://hooks.slack.com/actions/AABA1ABCD/1232321423432/D09sSasdasdAS9091209",
+		User:        slack.User{ID: "U1234"},
+		Channel:     slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C1H9RESGL"}}},
+		Container:   slack.Container{MessageTs: "1360782400.498405"},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: "rollback", Value: "v1.2.2"}},
+		},
+	}
+
+	resp := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(resp, interactionRequest(t, "/slack/interactions", callback))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+	require.IsType(t, BlockActionEvent{}, events[0])
+
+	actual := events[0].(BlockActionEvent)
+	assert.Equal(t, "U1234", actual.UserID)
+	assert.Equal(t, "C1H9RESGL", actual.ChannelID)
+	assert.Equal(t, "1360782400.498405", actual.MessageID)
+	assert.Equal(t, callback.TriggerID, actual.TriggerID)
+	assert.Equal(t, callback.ResponseURL, actual.ResponseURL)
+	require.Len(t, actual.Actions, 1)
+	assert.Equal(t, "rollback", actual.Actions[0].ActionID)
+	assert.Equal(t, "v1.2.2", actual.Actions[0].Value)
+}
+
+func TestEventsAPIServer_ViewSubmission(t *testing.T) {
+	s := newTestEventsAPIServerWithoutBrain(t, Config{
+		EventsAPI: EventsAPIConfig{InteractionsPath: "/slack/interactions"},
+	})
+
+	brain := joetest.NewBrain(t)
+	brain.RegisterHandler(func(evt ViewSubmissionEvent) {
+		if evt.CallbackID == "deploy" {
+			evt.RespondWithErrors(map[string]string{"version": "unknown version"})
+		}
+	})
+
+	go s.handleSlackEvents(brain.Brain)
+	defer brain.Finish()
+	defer s.Close()
+
+	callback := slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		User: slack.User{ID: "U1234"},
+		View: slack.View{CallbackID: "deploy"},
+	}
+
+	resp := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(resp, interactionRequest(t, "/slack/interactions", callback))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"response_action": "errors", "errors": {"version": "unknown version"}}`, resp.Body.String())
+
+	// Submissions without a response are simply acknowledged.
+	callback.View.CallbackID = "other"
+	resp = httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(resp, interactionRequest(t, "/slack/interactions", callback))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Body.String())
+}
+
+func TestEventsAPIServer_ViewSubmissionFullQueue(t *testing.T) {
+	// The event processing loop is not started yet so the queue fills up.
+	s := newTestEventsAPIServerWithoutBrain(t, Config{
+		EventsAPI: EventsAPIConfig{
+			InteractionsPath: "/slack/interactions",
+			QueueSize:        1,
+			QueuePolicy:      QueuePolicyBlock,
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+			Type:       slackevents.CallbackEvent,
+			InnerEvent: rawJSON(slackevents.MessageEvent{Type: slackevents.Message}),
+		}))
+		s.httpHandler(httptest.NewRecorder(), req)
+
+		for len(s.queue) > 0 && i == 0 {
+			time.Sleep(time.Millisecond) // wait until the queue worker took the event
+		}
+	}
+
+	callback := slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		User: slack.User{ID: "U1234"},
+		View: slack.View{CallbackID: "deploy"},
+	}
+
+	resp := httptest.NewRecorder()
+	done := make(chan bool)
+	go func() {
+		s.http.Handler.ServeHTTP(resp, interactionRequest(t, "/slack/interactions", callback))
+		done <- true
+	}()
+	time.Sleep(10 * time.Millisecond) // let the handler wait for room in the queue
+
+	brain := joetest.NewBrain(t)
+	brain.RegisterHandler(func(evt ViewSubmissionEvent) {
+		evt.RespondWithErrors(map[string]string{"version": "unknown version"})
+	})
+
+	go s.handleSlackEvents(brain.Brain)
+	defer brain.Finish()
+	defer s.Close()
+
+	// The response must not be written before the event was queued.
+	<-done
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Result().Header.Get("Content-Type"))
+	assert.JSONEq(t, `{"response_action": "errors", "errors": {"version": "unknown version"}}`, resp.Body.String())
+}
+
+func TestEventsAPIServer_InteractionInvalidToken(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t, Config{
+		VerificationToken: "secret",
+		EventsAPI:         EventsAPIConfig{InteractionsPath: "/slack/interactions"},
+	})
+
+	callback := slack.InteractionCallback{
+		Type:  slack.InteractionTypeShortcut,
+		Token: "wrong",
+	}
+
+	resp := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(resp, interactionRequest(t, "/slack/interactions", callback))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Empty(t, recordedEvents())
+}
+
+func TestAdapter_InteractionEvents(t *testing.T) {
+	brain := joetest.NewBrain(t)
+	a, _ := newTestAdapter(t)
+
+	done := make(chan bool)
+	go func() {
+		a.handleSlackEvents(brain.Brain)
+		done <- true
+	}()
+
+	callbacks := []*slack.InteractionCallback{
+		{Type: slack.InteractionTypeViewClosed, View: slack.View{CallbackID: "deploy"}, ViewClosedCallback: slack.ViewClosedCallback{IsCleared: true}},
+		{Type: slack.InteractionTypeShortcut, CallbackID: "new_deployment", TriggerID: "123"},
+		{Type: slack.InteractionTypeMessageAction, CallbackID: "translate", MessageTs: "1360782400.498405"},
+		{Type: "unknown"},
+	}
+
+	for _, cb := range callbacks {
+		evt, _ := newInteractionEvent(cb)
+		a.events <- evt
+	}
+
+	close(a.events)
+	<-done
+	brain.Finish()
+
+	events := brain.RecordedEvents()
+	require.Len(t, events, 3)
+	assert.Equal(t, ViewClosedEvent{CallbackID: "deploy", IsCleared: true, View: callbacks[0].View, Data: callbacks[0]}, events[0])
+	assert.Equal(t, ShortcutEvent{CallbackID: "new_deployment", TriggerID: "123", Data: callbacks[1]}, events[1])
+	assert.Equal(t, MessageActionEvent{CallbackID: "translate", MessageID: "1360782400.498405", Data: callbacks[2]}, events[2])
+}
@@ -0,0 +1,218 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// defaultMaxMessageLength is the maximum number of characters of a message
+// before it is split or uploaded as snippet. Slack recommends to keep messages
+// below 4000 characters and truncates them after 40000 characters.
+const defaultMaxMessageLength = 4000
+
+// minMaxMessageLength is the smallest maximum message length that can be
+// configured. It leaves enough room to reopen and close code fences.
+const minMaxMessageLength = 100
+
+// codeFence starts and ends a preformatted block of text in Slack.
+const codeFence = "```"
+
+// fenceOverhead returns the number of characters needed to open a code fence
+// with the given line at the beginning of a message and to close it at its end.
+// The line that opened a fence is moved to the next message if no other line of
+// the fence fits into the current one, so the overhead depends on this line
+// (e.g. "```go").
+func fenceOverhead(openLine string) int {
+	return utf8.RuneCountInString(openLine+"\n") + len("\n"+codeFence)
+}
+
+// LongMessageMode defines how the BotAdapter sends messages that are longer
+// than the configured maximum length.
+type LongMessageMode int
+
+// The available modes to send long messages.
+const (
+	// LongMessagesUnchanged sends long messages as they are which means
+	// Slack might truncate or reject them. This is the default.
+	LongMessagesUnchanged LongMessageMode = iota
+
+	// LongMessagesSplit splits long messages at line boundaries into multiple
+	// messages that are sent one after another.
+	LongMessagesSplit
+
+	// LongMessagesSnippet uploads long messages as text snippet.
+	LongMessagesSnippet
+)
+
+// snippetFilename is the name of the files that contain long messages that
+// are uploaded as snippet.
+const snippetFilename = "message.txt"
+
+// messageParts returns the parts in which the given text should be sent.
+func (a *BotAdapter) messageParts(text string) []string {
+	if a.longMessages.Mode != LongMessagesSplit {
+		return []string{text}
+	}
+
+	return splitMessage(text, a.longMessages.MaxLength)
+}
+
+// isLongMessage returns true if the text should be uploaded as snippet instead
+// of being sent as normal message.
+func (a *BotAdapter) isLongMessage(text string) bool {
+	return a.longMessages.Mode == LongMessagesSnippet &&
+		utf8.RuneCountInString(text) > a.longMessages.MaxLength
+}
+
+// sendSnippet uploads the text as snippet to the given channel. If the channel
+// contains a thread timestamp, the snippet is shared in this thread.
+func (a *BotAdapter) sendSnippet(text, channelID string) error {
+	_, err := a.uploadFile(channelID, slack.FileUploadParameters{
+		Content:  text,
+		Filetype: "text",
+		Filename: snippetFilename,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload message as snippet: %w", err)
+	}
+
+	return nil
+}
+
+// splitMessage splits the text at line boundaries into parts of at most
+// maxLength characters. Code fences that span multiple parts are closed at the
+// end of a part and reopened at the beginning of the next one. Lines that are
+// too long by themselves are wrapped at whitespace if possible.
+func splitMessage(text string, maxLength int) []string {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return []string{text}
+	}
+
+	s := &messageSplitter{maxLength: maxLength}
+	for _, line := range strings.Split(text, "\n") {
+		s.add(line)
+	}
+
+	s.flush()
+	return s.parts
+}
+
+// messageSplitter collects lines until they do not fit into a single message
+// anymore and keeps track of whether the current line is in a code fence.
+type messageSplitter struct {
+	maxLength  int
+	parts      []string
+	lines      []string
+	length     int    // number of characters of lines including line breaks
+	inFence    bool   // whether the last line ends within a code fence
+	fenceStart int    // index of the line that opened the current code fence
+	fenceLine  string // the line that opened the current code fence
+}
+
+func (s *messageSplitter) add(line string) {
+	// A line with an odd number of fence markers opens or closes a code fence.
+	toggle := strings.Count(line, codeFence)%2 == 1
+	inFence := s.inFence != toggle
+
+	// Lines in code fences must leave room to reopen and close the fence.
+	maxLineLength := s.maxLength
+	switch {
+	case s.inFence:
+		maxLineLength -= fenceOverhead(s.fenceLine)
+	case toggle:
+		maxLineLength -= fenceOverhead(codeFence)
+	}
+
+	n := utf8.RuneCountInString(line)
+	if n > maxLineLength {
+		for _, l := range wrapLine(line, maxLineLength) {
+			s.add(l)
+		}
+		return
+	}
+
+	length := s.length + n
+	if len(s.lines) > 0 {
+		length++ // line break
+	}
+	if inFence {
+		length += len("\n" + codeFence)
+	}
+
+	if len(s.lines) > 0 && length > s.maxLength {
+		s.flush()
+	}
+
+	if len(s.lines) > 0 {
+		s.length++
+	}
+
+	if inFence && !s.inFence {
+		s.fenceStart = len(s.lines)
+		s.fenceLine = line
+	}
+
+	s.lines = append(s.lines, line)
+	s.length += n
+	s.inFence = inFence
+}
+
+// flush finishes the current part and starts a new one.
+func (s *messageSplitter) flush() {
+	var next []string
+	inFence := s.inFence
+	if s.inFence {
+		next = []string{codeFence}
+
+		// Move a line that just opened the code fence to the next part instead
+		// of leaving an empty code block at the end of this one.
+		if last := len(s.lines) - 1; last > 0 && s.fenceStart == last {
+			next = s.lines[last:]
+			s.lines = s.lines[:last]
+			inFence = false
+		}
+	}
+
+	part := strings.Join(s.lines, "\n")
+	if inFence {
+		part += "\n" + codeFence
+	}
+
+	// Slack does not accept messages without text.
+	if strings.TrimSpace(part) != "" {
+		s.parts = append(s.parts, part)
+	}
+
+	s.lines = next
+	s.length = utf8.RuneCountInString(strings.Join(next, "\n"))
+	s.fenceStart = 0
+}
+
+// wrapLine splits a line into chunks of at most width characters. The line is
+// split at the last whitespace of each chunk unless this would make the chunk
+// less than half as long as allowed.
+func wrapLine(line string, width int) []string {
+	var chunks []string
+	runes := []rune(line)
+	for len(runes) > width {
+		end := width
+		for i := width; i > width/2; i-- {
+			if unicode.IsSpace(runes[i]) {
+				end = i
+				break
+			}
+		}
+
+		chunks = append(chunks, string(runes[:end]))
+		runes = runes[end:]
+		if unicode.IsSpace(runes[0]) {
+			runes = runes[1:] // drop the whitespace we split at
+		}
+	}
+
+	return append(chunks, string(runes))
+}
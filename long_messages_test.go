@@ -0,0 +1,178 @@
+package slack
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitMessage(t *testing.T) {
+	cases := map[string]struct {
+		text  string
+		parts []string
+	}{
+		"short": {
+			text:  "Hello World",
+			parts: []string{"Hello World"},
+		},
+		"lines": {
+			text: strings.Repeat("a", 60) + "\n" + strings.Repeat("b", 60) + "\n" + strings.Repeat("c", 30),
+			parts: []string{
+				strings.Repeat("a", 60),
+				strings.Repeat("b", 60) + "\n" + strings.Repeat("c", 30),
+			},
+		},
+		"code_fence": {
+			text: "Output:\n```\n" + strings.Repeat("a", 60) + "\n" + strings.Repeat("b", 60) + "\n```\nDone",
+			parts: []string{
+				"Output:\n```\n" + strings.Repeat("a", 60) + "\n```",
+				"```\n" + strings.Repeat("b", 60) + "\n```\nDone",
+			},
+		},
+		"move_fence_opener": {
+			text: strings.Repeat("a", 90) + "\n```\n" + strings.Repeat("b", 60) + "\n```",
+			parts: []string{
+				strings.Repeat("a", 90),
+				"```\n" + strings.Repeat("b", 60) + "\n```",
+			},
+		},
+		"tagged_code_fence": {
+			text: "```python\n" + strings.Repeat("a", 90) + "\n```",
+			parts: []string{
+				"```python\n" + strings.Repeat("a", 86) + "\n```",
+				"```\n" + strings.Repeat("a", 4) + "\n```",
+			},
+		},
+		"move_tagged_fence_opener": {
+			text: strings.Repeat("a", 80) + "\n```python\n" + strings.Repeat("b", 86) + "\n```",
+			parts: []string{
+				strings.Repeat("a", 80),
+				"```python\n" + strings.Repeat("b", 86) + "\n```",
+			},
+		},
+		"inline_code": {
+			text: "```" + strings.Repeat("a", 60) + "```\n" + strings.Repeat("b", 60),
+			parts: []string{
+				"```" + strings.Repeat("a", 60) + "```",
+				strings.Repeat("b", 60),
+			},
+		},
+		"long_line": {
+			text: strings.Repeat("word ", 50),
+			parts: []string{
+				strings.TrimSpace(strings.Repeat("word ", 20)),
+				strings.TrimSpace(strings.Repeat("word ", 20)),
+				strings.Repeat("word ", 10),
+			},
+		},
+		"long_line_in_code_fence": {
+			text: "```\n" + strings.Repeat("a", 150) + "\n```",
+			parts: []string{
+				"```\n" + strings.Repeat("a", 92) + "\n```",
+				"```\n" + strings.Repeat("a", 58) + "\n```",
+			},
+		},
+		"blank_line": {
+			text: strings.Repeat("a", 100) + "\n\n" + strings.Repeat("b", 10),
+			parts: []string{
+				strings.Repeat("a", 100),
+				"\n" + strings.Repeat("b", 10),
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			parts := splitMessage(c.text, 100)
+			assert.Equal(t, c.parts, parts)
+			for _, p := range parts {
+				assert.True(t, utf8.RuneCountInString(p) <= 100, "part is too long")
+				assert.Equal(t, 0, strings.Count(p, codeFence)%2, "code fences must be balanced")
+			}
+		})
+	}
+}
+
+func TestWrapLine(t *testing.T) {
+	assert.Equal(t, []string{"aaaa", "aaaa", "aa"}, wrapLine("aaaaaaaaaa", 4))
+	assert.Equal(t, []string{"foo bar", "baz"}, wrapLine("foo bar baz", 8))
+	assert.Equal(t, []string{"äöüäö", "üä"}, wrapLine("äöüäöüä", 5))
+}
+
+func TestAdapter_SendSplitMessage(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.longMessages = LongMessageConfig{Mode: LongMessagesSplit, MaxLength: 100}
+
+	var texts []string
+	expectPostMessage(slackAPI, a.context, "C1H9RESGL", 4).
+		Run(func(args mock.Arguments) {
+			var values url.Values
+			captureMsgValues(t, &values)(args)
+			texts = append(texts, values.Get("text"))
+		}).
+		Return("C1H9RESGL", "1360782400.498405", nil)
+
+	text := strings.Repeat("a", 60) + "\n" + strings.Repeat("b", 60)
+	err := a.Send(text, "C1H9RESGL")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{strings.Repeat("a", 60), strings.Repeat("b", 60)}, texts)
+}
+
+func TestAdapter_SendSplitMessageError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.longMessages = LongMessageConfig{Mode: LongMessagesSplit, MaxLength: 100}
+
+	expectPostMessage(slackAPI, a.context, "C1H9RESGL", 4).
+		Return("", "", errors.New("msg_too_long")).
+		Once()
+
+	text := strings.Repeat("a", 60) + "\n" + strings.Repeat("b", 60)
+	err := a.Send(text, "C1H9RESGL")
+	assert.EqualError(t, err, "failed to send part 1 of 2: msg_too_long")
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_SendSnippet(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.longMessages = LongMessageConfig{Mode: LongMessagesSnippet, MaxLength: 100}
+
+	text := strings.Repeat("a", 101)
+	slackAPI.On("UploadFileContext", a.context, slack.FileUploadParameters{
+		Content:         text,
+		Filetype:        "text",
+		Filename:        "message.txt",
+		Channels:        []string{"C1H9RESGL"},
+		ThreadTimestamp: "1360782400.498405",
+	}).Return(&slack.File{ID: "F1"}, nil)
+
+	err := a.Send(text, "C1H9RESGL/1360782400.498405")
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+
+	// Short messages are still sent as normal message.
+	expectPostMessage(slackAPI, a.context, "C1H9RESGL", 4).
+		Return("C1H9RESGL", "1360782400.498405", nil)
+
+	err = a.Send("Hello World", "C1H9RESGL")
+	require.NoError(t, err)
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_SendSnippetError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.longMessages = LongMessageConfig{Mode: LongMessagesSnippet, MaxLength: 100}
+
+	slackAPI.On("UploadFileContext", a.context, mock.Anything).
+		Return(nil, errors.New("not_in_channel"))
+
+	err := a.Send(strings.Repeat("a", 101), "C1H9RESGL")
+	assert.EqualError(t, err, "failed to upload message as snippet: not_in_channel")
+}
@@ -0,0 +1,129 @@
+package slack
+
+import (
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+)
+
+// The MemberJoinedChannelEvent is emitted when a user joins a channel that the
+// bot is a member of. With the Events API, Slack also uses this event to signal
+// that the bot itself joined a channel.
+//
+// See https://api.slack.com/events/member_joined_channel
+type MemberJoinedChannelEvent struct {
+	User         joe.User
+	Channel      string
+	ChannelType  string // "C" for public and "G" for private channels
+	InviterID    string // empty if the user joined by themselves
+	TeamID       string
+	EnterpriseID string
+}
+
+// The MemberLeftChannelEvent is emitted when a user leaves a channel that the
+// bot is a member of.
+//
+// See https://api.slack.com/events/member_left_channel
+type MemberLeftChannelEvent struct {
+	User         joe.User
+	Channel      string
+	ChannelType  string // "C" for public and "G" for private channels
+	TeamID       string
+	EnterpriseID string
+}
+
+// The ChannelJoinedEvent is emitted when the bot joined a channel or was invited
+// to one. This event is only sent via the Real Time Messaging (RTM) API.
+//
+// See https://api.slack.com/events/channel_joined
+type ChannelJoinedEvent struct {
+	Channel      string
+	TeamID       string
+	EnterpriseID string
+	Data         slack.Channel
+}
+
+// The ChannelLeftEvent is emitted when the bot left a channel or was removed
+// from it.
+//
+// See https://api.slack.com/events/channel_left
+type ChannelLeftEvent struct {
+	Channel      string
+	TeamID       string
+	EnterpriseID string
+}
+
+// The TeamJoinEvent is emitted when a new user joined the workspace.
+//
+// See https://api.slack.com/events/team_join
+type TeamJoinEvent struct {
+	User         joe.User
+	TeamID       string
+	EnterpriseID string
+	Data         slack.User
+}
+
+// See https://api.slack.com/events/member_joined_channel
+func (a *BotAdapter) handleMemberJoinedChannelEvent(ev *slack.MemberJoinedChannelEvent, brain joe.EventEmitter) {
+	brain.Emit(MemberJoinedChannelEvent{
+		User:         a.userByID(ev.User),
+		Channel:      ev.Channel,
+		ChannelType:  ev.ChannelType,
+		InviterID:    ev.Inviter,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+	})
+}
+
+// See https://api.slack.com/events/member_left_channel
+func (a *BotAdapter) handleMemberLeftChannelEvent(ev *slack.MemberLeftChannelEvent, brain joe.EventEmitter) {
+	brain.Emit(MemberLeftChannelEvent{
+		User:         a.userByID(ev.User),
+		Channel:      ev.Channel,
+		ChannelType:  ev.ChannelType,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+	})
+}
+
+// See https://api.slack.com/events/channel_joined
+func (a *BotAdapter) handleChannelJoinedEvent(ev *slack.ChannelJoinedEvent, brain joe.EventEmitter) {
+	// The event contains the complete channel object so we can use it to update
+	// the channel cache right away.
+	channel := ev.Channel
+	a.channels.add(&channel)
+
+	brain.Emit(ChannelJoinedEvent{
+		Channel:      ev.Channel.ID,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		Data:         ev.Channel,
+	})
+}
+
+// See https://api.slack.com/events/channel_left
+func (a *BotAdapter) handleChannelLeftEvent(ev *slack.ChannelLeftEvent, brain joe.EventEmitter) {
+	brain.Emit(ChannelLeftEvent{
+		Channel:      ev.Channel,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+	})
+}
+
+// See https://api.slack.com/events/team_join
+func (a *BotAdapter) handleTeamJoinEvent(ev *slack.TeamJoinEvent, brain joe.EventEmitter) {
+	// The event contains the complete user object so it can be cached and does
+	// not need to be looked up again.
+	user := ev.User
+	a.users.Add(&user)
+
+	brain.Emit(TeamJoinEvent{
+		User: joe.User{
+			ID:       user.ID,
+			Name:     user.Name,
+			RealName: user.RealName,
+		},
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		Data:         user,
+	})
+}
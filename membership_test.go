@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdapter_MemberJoinedAndLeftChannel(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	slackAPI.On("GetUserInfoContext", a.context, "U1234").Return(&slack.User{
+		ID:       "U1234",
+		Name:     "jd",
+		RealName: "John Doe",
+	}, nil).Once()
+
+	events := processTestEvents(t, a,
+		&slack.MemberJoinedChannelEvent{
+			Type:        "member_joined_channel",
+			User:        "U1234",
+			Channel:     "C1H9RESGL",
+			ChannelType: "C",
+			Inviter:     "U5678",
+		},
+		&slack.MemberLeftChannelEvent{
+			Type:        "member_left_channel",
+			User:        "U1234",
+			Channel:     "C1H9RESGL",
+			ChannelType: "C",
+		},
+	)
+
+	user := joe.User{ID: "U1234", Name: "jd", RealName: "John Doe"}
+	assert.Equal(t, []interface{}{
+		MemberJoinedChannelEvent{
+			User:        user,
+			Channel:     "C1H9RESGL",
+			ChannelType: "C",
+			InviterID:   "U5678",
+		},
+		MemberLeftChannelEvent{
+			User:        user,
+			Channel:     "C1H9RESGL",
+			ChannelType: "C",
+		},
+	}, events)
+
+	slackAPI.AssertExpectations(t)
+}
+
+func TestAdapter_ChannelJoinedAndLeft(t *testing.T) {
+	a, _ := newTestAdapter(t)
+
+	channel := slack.Channel{}
+	channel.ID = "C1H9RESGL"
+	channel.Name = "deployments"
+
+	group := slack.Channel{}
+	group.ID = "G1H9RESGL"
+	group.Name = "secret"
+
+	events := processTestEvents(t, a,
+		&slack.ChannelJoinedEvent{Type: "channel_joined", Channel: channel},
+		&slack.GroupJoinedEvent{Type: "group_joined", Channel: group},
+		&slack.ChannelLeftEvent{Type: "channel_left", Channel: "C1H9RESGL"},
+		&slack.GroupLeftEvent{Type: "group_left", Channel: "G1H9RESGL"},
+	)
+
+	assert.Equal(t, []interface{}{
+		ChannelJoinedEvent{Channel: "C1H9RESGL", Data: channel},
+		ChannelJoinedEvent{Channel: "G1H9RESGL", Data: group},
+		ChannelLeftEvent{Channel: "C1H9RESGL"},
+		ChannelLeftEvent{Channel: "G1H9RESGL"},
+	}, events)
+
+	// The joined channels should be cached.
+	cached, ok := a.channels.get("C1H9RESGL")
+	require.True(t, ok)
+	assert.Equal(t, "deployments", cached.Name)
+}
+
+func TestAdapter_TeamJoin(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+
+	user := slack.User{ID: "U1234", Name: "jd", RealName: "John Doe"}
+	events := processTestEvents(t, a, &slack.TeamJoinEvent{Type: "team_join", User: user})
+
+	assert.Equal(t, []interface{}{
+		TeamJoinEvent{
+			User: joe.User{ID: "U1234", Name: "jd", RealName: "John Doe"},
+			Data: user,
+		},
+	}, events)
+
+	// The new user should be cached so it is not requested via the API.
+	assert.Equal(t, joe.User{ID: "U1234", Name: "jd", RealName: "John Doe"}, a.userByID("U1234"))
+	slackAPI.AssertNotCalled(t, "GetUserInfoContext", a.context, "U1234")
+}
+
+func TestEventsAPIEvent_Membership(t *testing.T) {
+	a, _ := newTestAdapter(t)
+
+	cases := map[string]struct {
+		event    map[string]interface{}
+		expected interface{}
+	}{
+		"member_joined_channel": {
+			event: map[string]interface{}{
+				"type":         "member_joined_channel",
+				"user":         "U1234",
+				"channel":      "C1H9RESGL",
+				"channel_type": "C",
+				"inviter":      "U5678",
+			},
+			expected: &slack.MemberJoinedChannelEvent{
+				Type:        "member_joined_channel",
+				User:        "U1234",
+				Channel:     "C1H9RESGL",
+				ChannelType: "C",
+				Inviter:     "U5678",
+			},
+		},
+		"member_left_channel": {
+			event: map[string]interface{}{
+				"type":         "member_left_channel",
+				"user":         "U1234",
+				"channel":      "C1H9RESGL",
+				"channel_type": "C",
+			},
+			expected: &slack.MemberLeftChannelEvent{
+				Type:        "member_left_channel",
+				User:        "U1234",
+				Channel:     "C1H9RESGL",
+				ChannelType: "C",
+			},
+		},
+		"channel_left": {
+			event: map[string]interface{}{
+				"type":    "channel_left",
+				"channel": "C1H9RESGL",
+			},
+			expected: &slack.ChannelLeftEvent{
+				Type:    "channel_left",
+				Channel: "C1H9RESGL",
+			},
+		},
+		"team_join": {
+			event: map[string]interface{}{
+				"type": "team_join",
+				"user": map[string]string{"id": "U1234", "name": "jd"},
+			},
+			expected: &slack.TeamJoinEvent{
+				Type: "team_join",
+				User: slack.User{ID: "U1234", Name: "jd"},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			evt, ok := a.eventsAPIEvent(parseInnerEvent(t, c.event))
+			require.True(t, ok)
+			assert.Equal(t, name, evt.Type)
+			assert.Equal(t, c.expected, evt.Data)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package slack
+
+import (
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+)
+
+// The MessageEditedEvent is emitted when a user changes the text of a message.
+// Use the WithReceiveEditedMessages() option if edited messages should also be
+// emitted as joe.ReceiveMessageEvent.
+// See https://api.slack.com/events/message/message_changed
+type MessageEditedEvent struct {
+	Channel      string
+	ID           string // the timestamp of the edited message
+	OldText      string
+	NewText      string
+	AuthorID     string
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.MessageEvent
+}
+
+// The MessageDeletedEvent is emitted when a message is deleted.
+// See https://api.slack.com/events/message/message_deleted
+type MessageDeletedEvent struct {
+	Channel      string
+	ID           string // the timestamp of the deleted message
+	Text         string // the text of the message before it was deleted
+	AuthorID     string
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.MessageEvent
+}
+
+// See https://api.slack.com/events/message/message_changed
+func (a *BotAdapter) handleMessageChangedEvent(ev *slack.MessageEvent, brain joe.EventEmitter) {
+	if ev.SubMessage == nil {
+		return
+	}
+
+	msg := *ev.SubMessage
+	if msg.User == a.userID {
+		// message is from us, ignore it!
+		return
+	}
+
+	if !a.allowInnerMessage(ev.Channel, &msg) {
+		return
+	}
+
+	var oldText string
+	if ev.PreviousMessage != nil {
+		oldText = ev.PreviousMessage.Text
+		if oldText == msg.Text {
+			// The text did not change but Slack modified the message for
+			// another reason (e.g. to add link previews).
+			return
+		}
+	}
+
+	brain.Emit(MessageEditedEvent{
+		Channel:      ev.Channel,
+		ID:           msg.Timestamp,
+		OldText:      oldText,
+		NewText:      msg.Text,
+		AuthorID:     msg.User,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		Data:         ev,
+	})
+
+	if !a.receiveEditedMessages {
+		return
+	}
+
+	// The new version of the message does not contain the channel so we
+	// have to set it before we can process it like any other message.
+	msg.Channel = ev.Channel
+	a.handleMessageEvent(&slack.MessageEvent{Msg: msg}, brain)
+}
+
+// See https://api.slack.com/events/message/message_deleted
+func (a *BotAdapter) handleMessageDeletedEvent(ev *slack.MessageEvent, brain joe.EventEmitter) {
+	inner := ev.PreviousMessage
+	if inner == nil {
+		inner = new(slack.Msg)
+	}
+
+	if !a.allowInnerMessage(ev.Channel, inner) {
+		return
+	}
+
+	evt := MessageDeletedEvent{
+		Channel:      ev.Channel,
+		ID:           ev.DeletedTimestamp,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		Data:         ev,
+	}
+
+	if ev.PreviousMessage != nil {
+		evt.Text = ev.PreviousMessage.Text
+		evt.AuthorID = ev.PreviousMessage.User
+		if evt.ID == "" {
+			evt.ID = ev.PreviousMessage.Timestamp
+		}
+	}
+
+	brain.Emit(evt)
+}
+
+// allowInnerMessage applies the bot message filter and the channel and user
+// filters to the edited or deleted message of a message_changed or
+// message_deleted event, which itself has neither a user nor a bot ID.
+func (a *BotAdapter) allowInnerMessage(channelID string, msg *slack.Msg) bool {
+	inner := &slack.MessageEvent{Msg: *msg}
+	inner.Channel = channelID
+
+	return a.allowBotMessage(inner) && a.allowEvent(channelID, msg.User)
+}
@@ -0,0 +1,179 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-joe/joe"
+	"github.com/go-joe/joe/joetest"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func processTestEvents(t *testing.T, a *BotAdapter, events ...interface{}) []interface{} {
+	brain := joetest.NewBrain(t)
+
+	done := make(chan bool)
+	go func() {
+		a.handleSlackEvents(brain.Brain)
+		done <- true
+	}()
+
+	for _, evt := range events {
+		a.events <- slackEvent{Data: evt}
+	}
+
+	close(a.events)
+	<-done
+	brain.Finish()
+
+	return brain.RecordedEvents()
+}
+
+func newMessageChangedEvent(oldText, newText string) *slack.MessageEvent {
+	return &slack.MessageEvent{
+		Msg: slack.Msg{
+			SubType:   "message_changed",
+			Channel:   "D023BB3L2",
+			Timestamp: "1360782500.498405",
+			Hidden:    true,
+		},
+		SubMessage: &slack.Msg{
+			Type:      "message",
+			User:      "U1234",
+			Text:      newText,
+			Timestamp: "1360782400.498405",
+			Edited:    &slack.Edited{User: "U1234", Timestamp: "1360782500.498405"},
+		},
+		PreviousMessage: &slack.Msg{
+			Type:      "message",
+			User:      "U1234",
+			Text:      oldText,
+			Timestamp: "1360782400.498405",
+		},
+	}
+}
+
+func TestAdapter_MessageEditedEvent(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	evt := newMessageChangedEvent("deplyo billing", "deploy billing")
+
+	events := processTestEvents(t, a, evt)
+	require.Len(t, events, 1)
+
+	expected := MessageEditedEvent{
+		Channel:  "D023BB3L2",
+		ID:       "1360782400.498405",
+		OldText:  "deplyo billing",
+		NewText:  "deploy billing",
+		AuthorID: "U1234",
+		Data:     evt,
+	}
+	assert.Equal(t, expected, events[0])
+}
+
+func TestAdapter_MessageEditedEvent_ReceiveEditedMessages(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	expectConversation(slackAPI, a.context, "D023BB3L2", true)
+	a.receiveEditedMessages = true
+
+	events := processTestEvents(t, a,
+		newMessageChangedEvent("deplyo billing", "deploy billing"),
+		newMessageChangedEvent("unchanged", "unchanged"), // e.g. link previews
+	)
+
+	require.Len(t, events, 2)
+	assert.IsType(t, MessageEditedEvent{}, events[0])
+	require.IsType(t, joe.ReceiveMessageEvent{}, events[1])
+
+	msg := events[1].(joe.ReceiveMessageEvent)
+	assert.Equal(t, "deploy billing", msg.Text)
+	assert.Equal(t, "D023BB3L2", msg.Channel)
+	assert.Equal(t, "1360782400.498405", msg.ID)
+	assert.Equal(t, "U1234", msg.AuthorID)
+}
+
+func TestAdapter_MessageEditedEvent_IgnoreOwnMessages(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	a.receiveEditedMessages = true
+
+	evt := newMessageChangedEvent("deploying…", "deployed")
+	evt.SubMessage.User = a.userID
+
+	events := processTestEvents(t, a, evt)
+	assert.Empty(t, events)
+}
+
+func TestAdapter_MessageDeletedEvent(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	evt := &slack.MessageEvent{
+		Msg: slack.Msg{
+			SubType:          "message_deleted",
+			Channel:          "C1H9RESGL",
+			Timestamp:        "1360782500.498405",
+			DeletedTimestamp: "1360782400.498405",
+			Hidden:           true,
+		},
+		PreviousMessage: &slack.Msg{
+			User:      "U1234",
+			Text:      "Hello world",
+			Timestamp: "1360782400.498405",
+		},
+	}
+
+	events := processTestEvents(t, a, evt)
+	require.Len(t, events, 1)
+
+	expected := MessageDeletedEvent{
+		Channel:  "C1H9RESGL",
+		ID:       "1360782400.498405",
+		Text:     "Hello world",
+		AuthorID: "U1234",
+		Data:     evt,
+	}
+	assert.Equal(t, expected, events[0])
+}
+
+func TestEventsAPIServer_HandleMessageChangedEvent(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t)
+
+	req := httptest.NewRequest("POST", "/foo", toJSON(slackevents.EventsAPICallbackEvent{
+		Type: slackevents.CallbackEvent,
+		InnerEvent: rawJSON(slackevents.MessageEvent{
+			Type:      slackevents.Message,
+			SubType:   "message_changed",
+			Channel:   "C1H9RESGL",
+			TimeStamp: "1595070360",
+			Message: &slackevents.MessageEvent{
+				Type:      slackevents.Message,
+				User:      "U1234",
+				Text:      "new text",
+				TimeStamp: "1595070350",
+			},
+			PreviousMessage: &slackevents.MessageEvent{
+				Type:      slackevents.Message,
+				User:      "U1234",
+				Text:      "old text",
+				TimeStamp: "1595070350",
+			},
+		}),
+	}))
+
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+	require.IsType(t, MessageEditedEvent{}, events[0])
+
+	actual := events[0].(MessageEditedEvent)
+	assert.Equal(t, "C1H9RESGL", actual.Channel)
+	assert.Equal(t, "1595070350", actual.ID)
+	assert.Equal(t, "old text", actual.OldText)
+	assert.Equal(t, "new text", actual.NewText)
+	assert.Equal(t, "U1234", actual.AuthorID)
+}
@@ -0,0 +1,148 @@
+package slack
+
+import (
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// ConversationType is the type of a Slack conversation.
+type ConversationType string
+
+// All supported ConversationType values.
+const (
+	ConversationTypePublic  ConversationType = "public"  // public channel
+	ConversationTypePrivate ConversationType = "private" // private channel
+	ConversationTypeIM      ConversationType = "im"      // direct message
+	ConversationTypeMPIM    ConversationType = "mpim"    // multi-person direct message
+)
+
+// The EnrichedMessageEvent is passed as Data of a joe.ReceiveMessageEvent if
+// the WithMessageMetadata() option is used. It embeds the original
+// *slack.MessageEvent and additionally carries information about the author
+// and the channel of the message. Use the MessageEventFromData(…) function to
+// access the *slack.MessageEvent regardless of whether this option is used.
+type EnrichedMessageEvent struct {
+	*slack.MessageEvent
+	Author       AuthorInfo
+	Conversation ConversationInfo
+	TeamID       string
+	EnterpriseID string
+}
+
+// AuthorInfo contains information about the author of a message.
+type AuthorInfo struct {
+	ID       string
+	Name     string
+	RealName string
+	Email    string
+	TimeZone string
+	IsBot    bool
+	IsAdmin  bool
+
+	// The workspace and organization of the author. With Enterprise Grid
+	// and in shared channels, they can differ from those of the bot.
+	TeamID       string
+	EnterpriseID string
+	IsExternal   bool // true if the author belongs to another organization
+}
+
+// ConversationInfo contains information about the channel of a message.
+type ConversationInfo struct {
+	ID       string
+	Name     string // empty for direct messages
+	Type     ConversationType
+	IsMember bool // true if the bot is a member of the channel
+
+	IsShared    bool // true if the channel is shared with other workspaces
+	IsExtShared bool // true if the channel is shared with other organizations (Slack Connect)
+}
+
+// MessageEventFromData returns the *slack.MessageEvent that is passed as Data
+// of the joe.ReceiveMessageEvent types that are emitted by this adapter.
+func MessageEventFromData(data interface{}) (*slack.MessageEvent, bool) {
+	switch ev := data.(type) {
+	case *slack.MessageEvent:
+		return ev, true
+	case *EnrichedMessageEvent:
+		return ev.MessageEvent, ev.MessageEvent != nil
+	default:
+		return nil, false
+	}
+}
+
+// enrichMessageEvent resolves the author and channel of the given message. If
+// any of the lookups fail, the corresponding info only contains the ID.
+func (a *BotAdapter) enrichMessageEvent(ev *slack.MessageEvent) *EnrichedMessageEvent {
+	enriched := &EnrichedMessageEvent{
+		MessageEvent: ev,
+		Author:       AuthorInfo{ID: ev.User},
+		Conversation: ConversationInfo{ID: ev.Channel},
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+	}
+
+	if ev.User != "" {
+		user, err := a.slackUser(ev.User)
+		if err != nil {
+			a.logger.Error("Failed to get author of message",
+				zap.String("user_id", ev.User),
+				zap.Error(err),
+			)
+		} else {
+			enriched.Author = newAuthorInfo(user)
+			enriched.Author.IsExternal = a.isExternalUser(user)
+		}
+	}
+
+	channel, err := a.slackChannel(ev.Channel)
+	if err != nil {
+		a.logger.Error("Failed to get channel of message",
+			zap.String("channel_id", ev.Channel),
+			zap.Error(err),
+		)
+	} else {
+		enriched.Conversation = newConversationInfo(channel)
+	}
+
+	return enriched
+}
+
+func newAuthorInfo(user *slack.User) AuthorInfo {
+	return AuthorInfo{
+		ID:       user.ID,
+		Name:     user.Name,
+		RealName: user.RealName,
+		Email:    user.Profile.Email,
+		TimeZone: user.TZ,
+		IsBot:    user.IsBot,
+		IsAdmin:  user.IsAdmin,
+
+		TeamID:       user.TeamID,
+		EnterpriseID: user.Enterprise.EnterpriseID,
+	}
+}
+
+func newConversationInfo(channel *slack.Channel) ConversationInfo {
+	return ConversationInfo{
+		ID:       channel.ID,
+		Name:     channel.Name,
+		Type:     conversationType(channel),
+		IsMember: channel.IsMember,
+
+		IsShared:    channel.IsShared,
+		IsExtShared: channel.IsExtShared,
+	}
+}
+
+func conversationType(channel *slack.Channel) ConversationType {
+	switch {
+	case channel.IsIM:
+		return ConversationTypeIM
+	case channel.IsMpIM:
+		return ConversationTypeMPIM
+	case channel.IsPrivate || channel.IsGroup:
+		return ConversationTypePrivate
+	default:
+		return ConversationTypePublic
+	}
+}
@@ -0,0 +1,128 @@
+package slack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdapter_MessageMetadata(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.messageMetadata = true
+
+	slackAPI.On("GetUserInfoContext", a.context, "U1234").Return(&slack.User{
+		ID:       "U1234",
+		Name:     "jd",
+		RealName: "John Doe",
+		TZ:       "Europe/Berlin",
+		IsAdmin:  true,
+		Profile:  slack.UserProfile{Email: "jd@example.com"},
+	}, nil).Once()
+
+	channel := &slack.Channel{}
+	channel.ID = "C1H9RESGL"
+	channel.Name = "deployments"
+	channel.IsPrivate = true
+	channel.IsMember = true
+	slackAPI.On("GetConversationInfoContext", a.context, "C1H9RESGL", false).Return(channel, nil).Once()
+
+	evt := &slack.MessageEvent{
+		Msg: slack.Msg{
+			User:      "U1234",
+			Text:      "<@42> deploy",
+			Timestamp: "1360782400.498405",
+			Channel:   "C1H9RESGL",
+		},
+	}
+
+	events := processTestEvents(t, a, evt, evt)
+	require.Len(t, events, 2)
+	slackAPI.AssertExpectations(t)
+
+	msg := events[0].(joe.ReceiveMessageEvent)
+	require.IsType(t, new(EnrichedMessageEvent), msg.Data)
+
+	expected := &EnrichedMessageEvent{
+		MessageEvent: evt,
+		Author: AuthorInfo{
+			ID:       "U1234",
+			Name:     "jd",
+			RealName: "John Doe",
+			Email:    "jd@example.com",
+			TimeZone: "Europe/Berlin",
+			IsAdmin:  true,
+		},
+		Conversation: ConversationInfo{
+			ID:       "C1H9RESGL",
+			Name:     "deployments",
+			Type:     ConversationTypePrivate,
+			IsMember: true,
+		},
+	}
+	assert.Equal(t, expected, msg.Data)
+
+	raw, ok := MessageEventFromData(msg.Data)
+	require.True(t, ok)
+	assert.Equal(t, evt, raw)
+}
+
+func TestAdapter_MessageMetadataError(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	a.messageMetadata = true
+
+	slackAPI.On("GetUserInfoContext", a.context, "U1234").Return(nil, errors.New("invalid_auth"))
+	slackAPI.On("GetConversationInfoContext", a.context, "D023BB3L2", false).Return(nil, errors.New("channel_not_found"))
+
+	evt := &slack.MessageEvent{
+		Msg: slack.Msg{
+			User:      "U1234",
+			Text:      "<@42> Hello",
+			Timestamp: "1360782400.498405",
+			Channel:   "D023BB3L2",
+		},
+	}
+
+	events := processTestEvents(t, a, evt)
+	require.Len(t, events, 1)
+
+	expected := &EnrichedMessageEvent{
+		MessageEvent: evt,
+		Author:       AuthorInfo{ID: "U1234"},
+		Conversation: ConversationInfo{ID: "D023BB3L2"},
+	}
+	assert.Equal(t, expected, events[0].(joe.ReceiveMessageEvent).Data)
+}
+
+func TestMessageEventFromData(t *testing.T) {
+	evt := new(slack.MessageEvent)
+
+	actual, ok := MessageEventFromData(evt)
+	assert.True(t, ok)
+	assert.Equal(t, evt, actual)
+
+	actual, ok = MessageEventFromData(&EnrichedMessageEvent{MessageEvent: evt})
+	assert.True(t, ok)
+	assert.Equal(t, evt, actual)
+
+	_, ok = MessageEventFromData("foo")
+	assert.False(t, ok)
+}
+
+func TestConversationType(t *testing.T) {
+	cases := map[ConversationType]func(*slack.Channel){
+		ConversationTypePublic:  func(c *slack.Channel) {},
+		ConversationTypePrivate: func(c *slack.Channel) { c.IsPrivate = true },
+		ConversationTypeIM:      func(c *slack.Channel) { c.IsIM = true },
+		ConversationTypeMPIM:    func(c *slack.Channel) { c.IsMpIM = true; c.IsPrivate = true },
+	}
+
+	for expected, setup := range cases {
+		channel := new(slack.Channel)
+		setup(channel)
+		assert.Equal(t, expected, conversationType(channel))
+	}
+}
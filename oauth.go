@@ -0,0 +1,265 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultOAuthInstallPath  = "/slack/install"
+	defaultOAuthRedirectPath = "/slack/oauth_redirect"
+
+	// oauthAuthorizeURL is the URL to which users are sent to approve the
+	// installation of the app.
+	oauthAuthorizeURL = "https://slack.com/oauth/v2/authorize"
+
+	// oauthStateCookie is the name of the cookie that binds the OAuth state to
+	// the browser that started the installation.
+	oauthStateCookie = "slack_oauth_state"
+
+	// oauthStateTTL is the time users have to approve the installation.
+	oauthStateTTL = 10 * time.Minute
+)
+
+// oauthV2Response is the response of the "oauth.v2.access" API method. The
+// response type of the slack package does not contain all fields we need.
+type oauthV2Response struct {
+	slack.OAuthV2Response
+	IsEnterpriseInstall bool `json:"is_enterprise_install"`
+}
+
+// oauthInstallHandler starts the installation of the app by redirecting the
+// user to Slack.
+// See https://api.slack.com/authentication/oauth-v2#asking
+func (a *EventsAPIServer) oauthInstallHandler(w http.ResponseWriter, r *http.Request) {
+	state, err := a.newOAuthState(time.Now())
+	if err != nil {
+		a.logger.Error("Failed to create OAuth state", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("client_id", a.conf.OAuth.ClientID)
+	params.Set("scope", strings.Join(a.conf.OAuth.Scopes, ","))
+	params.Set("state", state)
+	if a.conf.OAuth.RedirectURL != "" {
+		params.Set("redirect_uri", a.conf.OAuth.RedirectURL)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     a.conf.OAuth.RedirectPath,
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, oauthAuthorizeURL+"?"+params.Encode(), http.StatusFound)
+}
+
+// oauthRedirectHandler completes the installation after the user approved it
+// by exchanging the temporary code for a bot token and saving the installation.
+// See https://api.slack.com/authentication/oauth-v2#exchanging
+func (a *EventsAPIServer) oauthRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// The state cookie is only needed once.
+	http.SetCookie(w, &http.Cookie{
+		Name:   oauthStateCookie,
+		Path:   a.conf.OAuth.RedirectPath,
+		MaxAge: -1,
+	})
+
+	if reason := query.Get("error"); reason != "" {
+		a.logger.Info("Installation of slack app was cancelled", zap.String("error", reason))
+		writeOAuthPage(w, http.StatusForbidden, "The installation was cancelled.")
+		return
+	}
+
+	var cookieState string
+	if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+		cookieState = cookie.Value
+	}
+
+	state := query.Get("state")
+	err := a.verifyOAuthState(state, time.Now())
+	if err == nil && !hmac.Equal([]byte(state), []byte(cookieState)) {
+		err = errors.New("state does not match cookie")
+	}
+	if err != nil {
+		a.logger.Warn("Rejected OAuth redirect with invalid state", zap.Error(err))
+		writeOAuthPage(w, http.StatusBadRequest, "The installation link is invalid or expired. Please try again.")
+		return
+	}
+
+	inst, err := a.exchangeOAuthCode(r, query.Get("code"))
+	if err != nil {
+		a.logger.Error("Failed to complete OAuth flow", zap.Error(err))
+		writeOAuthPage(w, http.StatusInternalServerError, "The installation failed. Please try again.")
+		return
+	}
+
+	err = a.conf.Installations.Save(*inst)
+	if err != nil {
+		a.logger.Error("Failed to save installation",
+			zap.String("team_id", inst.TeamID),
+			zap.String("enterprise_id", inst.EnterpriseID),
+			zap.Error(err),
+		)
+		writeOAuthPage(w, http.StatusInternalServerError, "The installation failed. Please try again.")
+		return
+	}
+
+	// The workspace might have been installed before with another token.
+	if a.workspaces != nil {
+		a.workspaces.forget(*inst)
+	}
+
+	a.logger.Info("Installed slack app",
+		zap.String("team_id", inst.TeamID),
+		zap.String("team", inst.TeamName),
+		zap.String("enterprise_id", inst.EnterpriseID),
+		zap.String("bot_user_id", inst.BotUserID),
+	)
+
+	name := inst.TeamName
+	if name == "" {
+		name = inst.EnterpriseName
+	}
+
+	writeOAuthPage(w, http.StatusOK, fmt.Sprintf("The app was installed in %s. You can close this window now.", name))
+}
+
+// exchangeOAuthCode requests a bot token for the temporary code of an approved
+// installation. We cannot use the slack package for this request since it
+// does not respect the configured API URL.
+func (a *EventsAPIServer) exchangeOAuthCode(r *http.Request, code string) (*Installation, error) {
+	if code == "" {
+		return nil, errors.New("missing OAuth code")
+	}
+
+	params := url.Values{}
+	params.Set("client_id", a.conf.OAuth.ClientID)
+	params.Set("client_secret", a.conf.OAuth.ClientSecret)
+	params.Set("code", code)
+	if a.conf.OAuth.RedirectURL != "" {
+		params.Set("redirect_uri", a.conf.OAuth.RedirectURL)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, a.apiURL+"oauth.v2.access", strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange OAuth code: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to exchange OAuth code: unexpected HTTP status code %d", httpResp.StatusCode)
+	}
+
+	var resp oauthV2Response
+	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode OAuth response: %w", err)
+	}
+
+	if !resp.Ok {
+		return nil, fmt.Errorf("failed to exchange OAuth code: %w", errors.New(resp.Error))
+	}
+
+	if resp.TokenType != "bot" || resp.AccessToken == "" {
+		return nil, errors.New("OAuth response does not contain a bot token")
+	}
+
+	inst := &Installation{
+		TeamID:         resp.Team.ID,
+		TeamName:       resp.Team.Name,
+		EnterpriseID:   resp.Enterprise.ID,
+		EnterpriseName: resp.Enterprise.Name,
+		BotToken:       resp.AccessToken,
+		BotUserID:      resp.BotUserID,
+		Scope:          resp.Scope,
+		InstalledAt:    time.Now(),
+	}
+
+	if resp.IsEnterpriseInstall {
+		// The app was installed for all workspaces of the organization.
+		inst.TeamID = ""
+		inst.TeamName = ""
+	}
+
+	return inst, nil
+}
+
+// newOAuthState returns a random state that is signed with the client secret
+// and expires after the oauthStateTTL.
+func (a *EventsAPIServer) newOAuthState(now time.Time) (string, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	payload := hex.EncodeToString(nonce) + "." + strconv.FormatInt(now.Add(oauthStateTTL).Unix(), 10)
+	return payload + "." + a.signOAuthState(payload), nil
+}
+
+// verifyOAuthState checks the signature and expiration of an OAuth state.
+func (a *EventsAPIServer) verifyOAuthState(state string, now time.Time) error {
+	i := strings.LastIndexByte(state, '.')
+	if i < 0 {
+		return errors.New("malformed state")
+	}
+
+	payload, signature := state[:i], state[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(a.signOAuthState(payload))) {
+		return errors.New("invalid state signature")
+	}
+
+	expires, err := strconv.ParseInt(payload[strings.IndexByte(payload, '.')+1:], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid state expiration: %w", err)
+	}
+
+	if now.After(time.Unix(expires, 0)) {
+		return errors.New("state is expired")
+	}
+
+	return nil
+}
+
+func (a *EventsAPIServer) signOAuthState(payload string) string {
+	mac := hmac.New(sha256.New, []byte(a.conf.OAuth.ClientSecret))
+	_, _ = mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeOAuthPage responds with a minimal HTML page that shows the result of
+// the installation to the user.
+func writeOAuthPage(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<!DOCTYPE html>\n<html><body><p>%s</p></body></html>\n", html.EscapeString(text))
+}
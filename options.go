@@ -3,7 +3,9 @@ package slack
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/slack-go/slack"
@@ -17,6 +19,7 @@ type Option func(*Config) error
 type Config struct {
 	Token             string
 	VerificationToken string
+	AppToken          string // app-level token, only required for Socket Mode
 	Name              string
 	Debug             bool
 	Logger            *zap.Logger
@@ -33,10 +36,163 @@ type Config struct {
 	// Listen and respond to all messages not just those directed at the Bot User.
 	ListenPassive bool
 
+	// Respond to messages in the thread they originated from, starting a new
+	// thread if the message was not sent in a thread already.
+	ThreadedResponses bool
+
+	// Also send all thread replies to the channel (i.e. "reply_broadcast").
+	ReplyBroadcast bool
+
+	// Emit edited messages as joe.ReceiveMessageEvent in addition to the
+	// MessageEditedEvent, so commands that were corrected by editing still run.
+	ReceiveEditedMessages bool
+
+	// Pass an *EnrichedMessageEvent instead of the *slack.MessageEvent as Data
+	// of all emitted joe.ReceiveMessageEvent events.
+	MessageMetadata bool
+
+	// UserCache stores the users that were looked up via the Slack API.
+	// Defaults to an LRU cache of 5000 users that expire after one hour.
+	UserCache UserCache
+
+	// Load all users of the workspace into the UserCache when the adapter
+	// starts and then again every UserCacheRefreshInterval (if not zero).
+	PrewarmUserCache         bool
+	UserCacheRefreshInterval time.Duration
+
+	// RateLimit configures how requests to the Slack API are rate limited.
+	RateLimit RateLimitConfig
+
+	// OutboundQueue configures the optional queue for outgoing messages.
+	OutboundQueue OutboundQueueConfig
+
+	// LongMessages configures how messages are sent that exceed the maximum
+	// message length.
+	LongMessages LongMessageConfig
+
+	// EventSampling configures the optional sampling of event payloads to
+	// debug unknown or changed events.
+	EventSampling EventSamplingConfig
+
+	// Filter restricts the channels and users whose messages and reactions
+	// are passed to the brain.
+	Filter FilterConfig
+
+	// BotMessages configures how messages of other bots and integrations
+	// are handled.
+	BotMessages BotMessageConfig
+
 	// Options if you want to use the Slack Events API. Ignored on the normal RTM adapter.
 	EventsAPI EventsAPIConfig
 }
 
+// OutboundQueueConfig contains the configuration of the outbound queue which
+// stores messages until they have been sent successfully.
+type OutboundQueueConfig struct {
+	// Store persists all messages that have not been sent yet. The outbound
+	// queue is disabled if this is nil.
+	Store OutboundStore
+
+	// MinBackoff and MaxBackoff define the range of the delay between two
+	// attempts to send a message. Default to one second and five minutes.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// DeadLetter is called for messages that failed permanently (e.g. because
+	// the channel does not exist). By default such messages are logged.
+	DeadLetter func(msg OutboundMessage, err error)
+}
+
+// LongMessageConfig contains the configuration of how the BotAdapter sends
+// messages that are longer than MaxLength characters.
+type LongMessageConfig struct {
+	// Mode defines whether long messages are sent unchanged (the default), split
+	// into multiple messages or uploaded as snippet.
+	Mode LongMessageMode
+
+	// MaxLength is the maximum number of characters of a single message.
+	// Defaults to 4000 characters.
+	MaxLength int
+}
+
+// EventSamplingConfig contains the configuration of the event sampling which
+// passes the redacted payloads of a random sample of events to an EventSink.
+type EventSamplingConfig struct {
+	// Sink receives the sampled events. Event sampling is disabled if this is nil.
+	Sink EventSink
+
+	// Rate is the fraction of events that are sampled (between 0 and 1).
+	// Defaults to 1 (i.e. all events).
+	Rate float64
+
+	// MaxPerMinute limits the number of samples per minute. Defaults to 10.
+	MaxPerMinute int
+
+	// AllEvents enables sampling of the raw payloads of all events instead of
+	// only those of unknown event types.
+	AllEvents bool
+}
+
+// FilterConfig contains the channels and users whose messages and reactions
+// the adapter passes to the brain. Channels and users can be given by their ID
+// or by their name (e.g. "#general" or "@jd"). All events pass the filter if
+// it is empty.
+type FilterConfig struct {
+	// AllowedChannels are the only channels whose events are passed to the
+	// brain. All channels are allowed if it is empty.
+	AllowedChannels []string
+
+	// DeniedChannels are channels whose events are always dropped, even if
+	// they are allowed via AllowedChannels.
+	DeniedChannels []string
+
+	// AllowedUsers are the only users whose events are passed to the brain.
+	// All users are allowed if it is empty.
+	AllowedUsers []string
+
+	// DenyExternalUsers drops all events of users of other organizations
+	// (e.g. via Slack Connect).
+	DenyExternalUsers bool
+}
+
+// BotMessageConfig contains the configuration of how the adapter handles
+// messages of other bots and integrations. By default they are passed to the
+// brain like all other messages.
+type BotMessageConfig struct {
+	// Ignore drops all messages of bots except those in AllowedBots.
+	Ignore bool
+
+	// AllowedBots contains the bot IDs (e.g. "B01234567") or app IDs (e.g.
+	// "A01234567") of bots whose messages are not ignored.
+	AllowedBots []string
+
+	// MaxReplies limits how many messages of the same bot in the same channel
+	// the adapter passes to the brain within Window, which protects against
+	// loops in which two bots keep replying to each other. Zero means no limit.
+	MaxReplies int
+
+	// Window is the time window of MaxReplies. Defaults to one minute.
+	Window time.Duration
+}
+
+// RateLimitConfig contains the configuration of the rate limiter that delays
+// requests to the Slack API according to the documented rate limits of Slack.
+// See https://api.slack.com/docs/rate-limits
+type RateLimitConfig struct {
+	// Enabled turns on the rate limiter. It is disabled by default.
+	Enabled bool
+
+	// MaxWait is the maximum time a request waits for the rate limiter.
+	// Requests that would have to wait longer fail immediately with the
+	// ErrRateLimitMaxWait error. Defaults to one minute.
+	MaxWait time.Duration
+
+	// MaxQueue is the maximum number of requests that wait for the rate
+	// limiter at the same time. Further requests fail immediately with the
+	// ErrRateLimitQueueFull error. Zero means no limit.
+	MaxQueue int
+}
+
 // EventsAPIConfig contains the configuration of an EventsAPIServer.
 type EventsAPIConfig struct {
 	Middleware        func(next http.Handler) http.Handler
@@ -45,21 +201,99 @@ type EventsAPIConfig struct {
 	WriteTimeout      time.Duration
 	TLSConf           *tls.Config
 	CertFile, KeyFile string
+
+	// SigningSecret is used to verify the signature of each request. If it is
+	// set, the legacy verification token is not checked anymore.
+	// See https://api.slack.com/authentication/verifying-requests-from-slack
+	SigningSecret string
+
+	// SignatureMaxAge is the maximum difference between the request timestamp
+	// of a signed request and the local time. Older requests are rejected to
+	// protect against replay attacks. Defaults to five minutes.
+	SignatureMaxAge time.Duration
+
+	// QueueSize is the maximum number of received events that are waiting to
+	// be processed. Defaults to 100.
+	QueueSize int
+
+	// QueuePolicy decides what happens to new events if the queue is full.
+	QueuePolicy QueuePolicy
+
+	// Deduplication is used to ignore events that Slack delivered more than
+	// once (e.g. because of retries). Defaults to an in-memory store with a
+	// TTL of one hour.
+	Deduplication DeduplicationStore
+
+	// InteractionsPath is the HTTP path on which the server receives payloads
+	// of interactive components (e.g. button clicks or modal submissions).
+	// Interactive components are disabled if this is empty.
+	InteractionsPath string
+
+	// SlashCommandsPath is the HTTP path on which the server receives slash
+	// command requests. Slash commands are disabled if this is empty.
+	SlashCommandsPath string
+
+	// NoRetry makes the server ask Slack to not retry failed deliveries by
+	// setting the "X-Slack-No-Retry" header on all responses.
+	NoRetry bool
+
+	// Installations contains the bot tokens of all workspaces in which the
+	// app is installed. If it is set, events are processed with the token and
+	// bot user of the workspace that sent them and Config.Token is optional.
+	Installations InstallationStore
+
+	// OAuth enables the built-in handlers to install the app via the
+	// OAuth v2 flow. OAuth is disabled if the client ID is empty.
+	OAuth OAuthConfig
+}
+
+// OAuthConfig contains the settings of the OAuth v2 flow that is used to
+// install the app in new workspaces.
+// See https://api.slack.com/authentication/oauth-v2
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string // the bot scopes that are requested during installation
+
+	// RedirectURL is the public URL of the redirect handler. If it is empty,
+	// Slack uses the first redirect URL of the app settings.
+	RedirectURL string
+
+	// InstallPath is the HTTP path that starts the installation. Defaults to
+	// "/slack/install".
+	InstallPath string
+
+	// RedirectPath is the HTTP path to which Slack redirects users after
+	// they approved the installation. Defaults to "/slack/oauth_redirect".
+	RedirectPath string
 }
 
+// A QueuePolicy decides how the EventsAPIServer handles new events if its
+// event queue is full.
+type QueuePolicy int
+
+// All supported QueuePolicy values.
+const (
+	// QueuePolicyBlock acknowledges the request and then waits until there is
+	// room in the queue. This is the default.
+	QueuePolicyBlock QueuePolicy = iota
+
+	// QueuePolicyDrop acknowledges the request but discards the event.
+	QueuePolicyDrop
+
+	// QueuePolicyReject responds with "503 Service Unavailable" so Slack will
+	// retry to deliver the event later. The SocketModeClient acknowledges
+	// events before they are queued and therefore waits like QueuePolicyBlock.
+	QueuePolicyReject
+)
+
 func (conf Config) slackOptions() []slack.Option {
 	if conf.Logger == nil {
 		conf.Logger = zap.NewNop()
 	}
-	if conf.SlackAPIURL == "" {
-		conf.SlackAPIURL = slack.APIURL
-	}
-	if conf.SlackAPIURL[len(conf.SlackAPIURL)-1] != '/' {
-		conf.SlackAPIURL += "/"
-	}
 
 	opts := []slack.Option{
-		slack.OptionAPIURL(conf.SlackAPIURL),
+		slack.OptionAPIURL(conf.apiURL()),
 	}
 
 	if conf.Debug {
@@ -72,6 +306,19 @@ func (conf Config) slackOptions() []slack.Option {
 	return opts
 }
 
+// apiURL returns the base URL of the Slack API, always ending in a slash.
+func (conf Config) apiURL() string {
+	u := conf.SlackAPIURL
+	if u == "" {
+		u = slack.APIURL
+	}
+	if u[len(u)-1] != '/' {
+		u += "/"
+	}
+
+	return u
+}
+
 // WithLogger can be used to inject a different logger for the slack adapater.
 func WithLogger(logger *zap.Logger) Option {
 	return func(conf *Config) error {
@@ -115,6 +362,51 @@ func WithListenPassive() Option {
 	}
 }
 
+// WithThreadedResponses makes the adapter respond to all messages in a thread.
+// If the message was sent in a thread already, the response is sent to the same
+// thread, otherwise a new thread is started on the message.
+//
+// This works by appending the thread timestamp to the Channel field of all
+// emitted joe.ReceiveMessageEvent events (e.g. "C024BE91L/1360782400.498405")
+// so handlers that compare channel IDs need to take this into account.
+func WithThreadedResponses() Option {
+	return func(conf *Config) error {
+		conf.ThreadedResponses = true
+		return nil
+	}
+}
+
+// WithReceiveEditedMessages makes the adapter emit a joe.ReceiveMessageEvent
+// with the new text whenever a user edits a message. This way users can fix
+// a typo in a command and the bot will still run it. Edits that do not change
+// the text of a message (e.g. when Slack adds link previews) are ignored.
+func WithReceiveEditedMessages() Option {
+	return func(conf *Config) error {
+		conf.ReceiveEditedMessages = true
+		return nil
+	}
+}
+
+// WithMessageMetadata makes the adapter resolve the author and channel of all
+// received messages and pass them as *EnrichedMessageEvent in the Data field of
+// the emitted joe.ReceiveMessageEvent. The EnrichedMessageEvent embeds the
+// original *slack.MessageEvent which can be accessed via MessageEventFromData(…).
+func WithMessageMetadata() Option {
+	return func(conf *Config) error {
+		conf.MessageMetadata = true
+		return nil
+	}
+}
+
+// WithReplyBroadcast makes the adapter also send all thread replies to the
+// channel the thread belongs to.
+func WithReplyBroadcast() Option {
+	return func(conf *Config) error {
+		conf.ReplyBroadcast = true
+		return nil
+	}
+}
+
 // WithTLS is an option for the EventsAPIServer that enables serving HTTP
 // requests via TLS.
 func WithTLS(certFile, keyFile string) Option {
@@ -178,3 +470,366 @@ func WithMiddleware(mw func(next http.Handler) http.Handler) Option {
 		return nil
 	}
 }
+
+// WithSigningSecret is an option for the EventsAPIServer that enables
+// verifying the signature of all incoming requests using the signing secret of
+// your Slack app. This replaces the deprecated verification token.
+func WithSigningSecret(secret string) Option {
+	return func(conf *Config) error {
+		if secret == "" {
+			return errors.New("signing secret cannot be empty")
+		}
+
+		conf.EventsAPI.SigningSecret = secret
+		return nil
+	}
+}
+
+// WithSignatureMaxAge is an option for the EventsAPIServer that sets the
+// maximum age of signed requests. This option only has an effect in combination
+// with the WithSigningSecret(…) option.
+func WithSignatureMaxAge(d time.Duration) Option {
+	return func(conf *Config) error {
+		conf.EventsAPI.SignatureMaxAge = d
+		return nil
+	}
+}
+
+// WithEventQueue is an option for the EventsAPIServer and the SocketModeClient
+// that configures the size of the queue of events that have been acknowledged
+// but not processed yet, as well as what should happen if this queue is full.
+func WithEventQueue(size int, policy QueuePolicy) Option {
+	return func(conf *Config) error {
+		if size <= 0 {
+			return errors.New("event queue size must be greater than zero")
+		}
+
+		conf.EventsAPI.QueueSize = size
+		conf.EventsAPI.QueuePolicy = policy
+		return nil
+	}
+}
+
+// WithDeduplicationStore is an option for the EventsAPIServer that sets the
+// store that is used to detect events which Slack delivered more than once.
+// You can use the NewJoeMemoryDeduplicationStore(…) function to keep the event
+// IDs in the bot's memory so they survive restarts.
+func WithDeduplicationStore(store DeduplicationStore) Option {
+	return func(conf *Config) error {
+		if store == nil {
+			return errors.New("deduplication store cannot be nil")
+		}
+
+		conf.EventsAPI.Deduplication = store
+		return nil
+	}
+}
+
+// WithNoRetry is an option for the EventsAPIServer that asks Slack to never
+// retry the delivery of events, even if the server responded with an error.
+func WithNoRetry() Option {
+	return func(conf *Config) error {
+		conf.EventsAPI.NoRetry = true
+		return nil
+	}
+}
+
+// WithInteractionsPath is an option for the EventsAPIServer that enables
+// receiving payloads of interactive components (e.g. button clicks, select
+// menus or modal submissions) on the given HTTP path. This path must be
+// configured as "Request URL" in the "Interactivity & Shortcuts" settings of
+// your Slack app.
+func WithInteractionsPath(path string) Option {
+	return func(conf *Config) error {
+		if !strings.HasPrefix(path, "/") {
+			return errors.New("interactions path must start with a slash")
+		}
+
+		conf.EventsAPI.InteractionsPath = path
+		return nil
+	}
+}
+
+// WithSlashCommandsPath is an option for the EventsAPIServer that enables
+// receiving slash commands on the given HTTP path. This path must be configured
+// as "Request URL" of each slash command in the settings of your Slack app.
+func WithSlashCommandsPath(path string) Option {
+	return func(conf *Config) error {
+		if !strings.HasPrefix(path, "/") {
+			return errors.New("slash commands path must start with a slash")
+		}
+
+		conf.EventsAPI.SlashCommandsPath = path
+		return nil
+	}
+}
+
+// WithInstallationStore is an option for the EventsAPIServer that lets it
+// serve all workspaces in which the app is installed. The adapter looks up the
+// bot token and bot user of each workspace in the store and emits the team ID
+// with all events. If a store is configured, the token of the adapter may be
+// empty.
+func WithInstallationStore(store InstallationStore) Option {
+	return func(conf *Config) error {
+		if store == nil {
+			return errors.New("installation store must not be nil")
+		}
+
+		conf.EventsAPI.Installations = store
+		return nil
+	}
+}
+
+// WithOAuth is an option for the EventsAPIServer that enables the OAuth v2
+// flow to install the app in new workspaces. Users start the installation on
+// the "/slack/install" path and are redirected to "/slack/oauth_redirect"
+// afterwards, which saves the installation in the InstallationStore (see
+// WithInstallationStore).
+func WithOAuth(clientID, clientSecret string, scopes ...string) Option {
+	return func(conf *Config) error {
+		if clientID == "" || clientSecret == "" {
+			return errors.New("OAuth client ID and secret must not be empty")
+		}
+
+		if len(scopes) == 0 {
+			return errors.New("at least one OAuth scope is required")
+		}
+
+		conf.EventsAPI.OAuth.ClientID = clientID
+		conf.EventsAPI.OAuth.ClientSecret = clientSecret
+		conf.EventsAPI.OAuth.Scopes = scopes
+		return nil
+	}
+}
+
+// WithUserCache is an option to replace the default cache of the users that
+// the adapter looked up via the Slack API. See NewUserCache(…) to create an LRU
+// cache with a custom size and TTL.
+func WithUserCache(cache UserCache) Option {
+	return func(conf *Config) error {
+		if cache == nil {
+			return errors.New("user cache must not be nil")
+		}
+
+		conf.UserCache = cache
+		return nil
+	}
+}
+
+// WithUserCachePrewarming makes the adapter load all users of the workspace
+// into the user cache when it starts. If the refresh interval is not zero, the
+// users are loaded again in this interval. Note that the cache should be large
+// enough to hold all users of the workspace.
+func WithUserCachePrewarming(refreshInterval time.Duration) Option {
+	return func(conf *Config) error {
+		conf.PrewarmUserCache = true
+		conf.UserCacheRefreshInterval = refreshInterval
+		return nil
+	}
+}
+
+// WithRateLimit enables the rate limiter that delays requests to the Slack API
+// so they do not exceed the documented rate limits of Slack (e.g. one message
+// per second and channel). Requests that Slack rejects because of its rate
+// limits are automatically retried after the time Slack asked us to wait.
+//
+// Requests that would have to wait longer than maxWait fail with the
+// ErrRateLimitMaxWait error. If more than maxQueue requests are waiting at the
+// same time, further requests fail with the ErrRateLimitQueueFull error.
+// A maxQueue of zero means there is no limit.
+func WithRateLimit(maxWait time.Duration, maxQueue int) Option {
+	return func(conf *Config) error {
+		if maxWait <= 0 {
+			return errors.New("rate limit max wait must be positive")
+		}
+		if maxQueue < 0 {
+			return errors.New("rate limit queue length must not be negative")
+		}
+
+		conf.RateLimit = RateLimitConfig{
+			Enabled:  true,
+			MaxWait:  maxWait,
+			MaxQueue: maxQueue,
+		}
+		return nil
+	}
+}
+
+// WithOutboundQueue makes the adapter store all messages that are sent via
+// BotAdapter.Send(…) in the given store and send them asynchronously. If
+// sending a message fails, it is retried with an exponential backoff until it
+// succeeds. Messages to the same channel are always sent in order. Messages
+// that are still pending when the bot shuts down are sent on the next start.
+//
+// See NewJoeMemoryOutboundStore(…) and NewFileOutboundStore(…) for the
+// available stores and WithDeadLetterHandler(…) to handle messages that
+// failed permanently.
+func WithOutboundQueue(store OutboundStore) Option {
+	return func(conf *Config) error {
+		if store == nil {
+			return errors.New("outbound store must not be nil")
+		}
+
+		conf.OutboundQueue.Store = store
+		return nil
+	}
+}
+
+// WithDeadLetterHandler registers a function that is called for all messages
+// of the outbound queue that failed permanently (e.g. with "channel_not_found").
+func WithDeadLetterHandler(fun func(msg OutboundMessage, err error)) Option {
+	return func(conf *Config) error {
+		if fun == nil {
+			return errors.New("dead letter handler must not be nil")
+		}
+
+		conf.OutboundQueue.DeadLetter = fun
+		return nil
+	}
+}
+
+// WithMessageSplitting makes the adapter split messages that are longer than
+// maxLength characters at line boundaries and send them as multiple messages.
+// Code blocks are closed at the end of each message and reopened in the next
+// one. If maxLength is zero, messages are split after 4000 characters.
+func WithMessageSplitting(maxLength int) Option {
+	return withLongMessages(LongMessagesSplit, maxLength)
+}
+
+// WithLongMessagesAsSnippet makes the adapter upload messages that are longer
+// than maxLength characters as text snippet instead of sending them as normal
+// message. If maxLength is zero, messages longer than 4000 characters are
+// uploaded.
+func WithLongMessagesAsSnippet(maxLength int) Option {
+	return withLongMessages(LongMessagesSnippet, maxLength)
+}
+
+func withLongMessages(mode LongMessageMode, maxLength int) Option {
+	return func(conf *Config) error {
+		if maxLength != 0 && maxLength < minMaxMessageLength {
+			return fmt.Errorf("maximum message length must be at least %d characters", minMaxMessageLength)
+		}
+
+		conf.LongMessages = LongMessageConfig{
+			Mode:      mode,
+			MaxLength: maxLength,
+		}
+		return nil
+	}
+}
+
+// WithEventSampling passes the payloads of a random sample of the events that
+// the adapter does not know how to handle to the given EventSink. The rate
+// is the fraction of events that are sampled (between 0 and 1). At most ten
+// events are sampled per minute. Sensitive fields like the message text or
+// tokens are redacted.
+//
+// This can be used to diagnose changes of the Slack payloads in production
+// without flooding the logs.
+func WithEventSampling(sink EventSink, rate float64) Option {
+	return withEventSampling(sink, rate, false)
+}
+
+// WithRawEventSampling is like WithEventSampling but samples the raw payloads
+// of all received events, not only of those that are unknown.
+func WithRawEventSampling(sink EventSink, rate float64) Option {
+	return withEventSampling(sink, rate, true)
+}
+
+func withEventSampling(sink EventSink, rate float64, allEvents bool) Option {
+	return func(conf *Config) error {
+		if sink == nil {
+			return errors.New("event sink must not be nil")
+		}
+		if rate <= 0 || rate > 1 {
+			return errors.New("event sampling rate must be greater than 0 and at most 1")
+		}
+
+		conf.EventSampling.Sink = sink
+		conf.EventSampling.Rate = rate
+		conf.EventSampling.AllEvents = allEvents
+		return nil
+	}
+}
+
+// WithAllowedChannels restricts the channels in which the bot receives messages
+// and reactions. Channels can be given by their ID or by their name (e.g.
+// "#general"). Direct messages are only received if their ID is allowed.
+// Calling this option multiple times adds to the allowed channels.
+func WithAllowedChannels(channels ...string) Option {
+	return func(conf *Config) error {
+		if len(channels) == 0 {
+			return errors.New("at least one allowed channel is required")
+		}
+
+		conf.Filter.AllowedChannels = append(conf.Filter.AllowedChannels, channels...)
+		return nil
+	}
+}
+
+// WithDeniedChannels makes the bot ignore all messages and reactions in the
+// given channels, which can be given by their ID or by their name (e.g.
+// "#general"). Denied channels take precedence over allowed channels.
+func WithDeniedChannels(channels ...string) Option {
+	return func(conf *Config) error {
+		if len(channels) == 0 {
+			return errors.New("at least one denied channel is required")
+		}
+
+		conf.Filter.DeniedChannels = append(conf.Filter.DeniedChannels, channels...)
+		return nil
+	}
+}
+
+// WithAllowedUsers restricts the users whose messages and reactions the bot
+// receives. Users can be given by their ID or by their name (e.g. "@jd").
+func WithAllowedUsers(users ...string) Option {
+	return func(conf *Config) error {
+		if len(users) == 0 {
+			return errors.New("at least one allowed user is required")
+		}
+
+		conf.Filter.AllowedUsers = append(conf.Filter.AllowedUsers, users...)
+		return nil
+	}
+}
+
+// WithDenyExternalUsers makes the bot ignore all messages and reactions of
+// users of other organizations, e.g. in channels that are shared via Slack
+// Connect.
+func WithDenyExternalUsers() Option {
+	return func(conf *Config) error {
+		conf.Filter.DenyExternalUsers = true
+		return nil
+	}
+}
+
+// WithIgnoreBotMessages makes the adapter ignore all messages of other bots and
+// integrations, except of those whose bot ID (e.g. "B01234567") or app ID
+// (e.g. "A01234567") is passed as allowedBots.
+func WithIgnoreBotMessages(allowedBots ...string) Option {
+	return func(conf *Config) error {
+		conf.BotMessages.Ignore = true
+		conf.BotMessages.AllowedBots = append(conf.BotMessages.AllowedBots, allowedBots...)
+		return nil
+	}
+}
+
+// WithBotLoopDetection makes the adapter ignore the messages of another bot in
+// a channel if it already received more than maxReplies of its messages
+// there within the given time window. This breaks loops in which two bots
+// keep replying to each other. If window is zero, it defaults to one minute.
+func WithBotLoopDetection(maxReplies int, window time.Duration) Option {
+	return func(conf *Config) error {
+		if maxReplies <= 0 {
+			return errors.New("maximum number of replies to bots must be positive")
+		}
+		if window < 0 {
+			return errors.New("bot loop detection window must not be negative")
+		}
+
+		conf.BotMessages.MaxReplies = maxReplies
+		conf.BotMessages.Window = window
+		return nil
+	}
+}
@@ -1,8 +1,10 @@
 package slack
 
 import (
+	"bytes"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/go-joe/joe"
 	"github.com/slack-go/slack"
@@ -104,3 +106,300 @@ func TestWithMiddleware(t *testing.T) {
 	conf.EventsAPI.Middleware(nil)
 	assert.True(t, ok)
 }
+
+func TestWithSigningSecret(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithSigningSecret("my-signing-secret"),
+		WithSignatureMaxAge(time.Minute),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "my-signing-secret", conf.EventsAPI.SigningSecret)
+	assert.Equal(t, time.Minute, conf.EventsAPI.SignatureMaxAge)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithSigningSecret(""),
+	})
+	assert.EqualError(t, err, "signing secret cannot be empty")
+}
+
+func TestWithEventQueue(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithEventQueue(42, QueuePolicyReject),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 42, conf.EventsAPI.QueueSize)
+	assert.Equal(t, QueuePolicyReject, conf.EventsAPI.QueuePolicy)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithEventQueue(0, QueuePolicyDrop),
+	})
+	assert.EqualError(t, err, "event queue size must be greater than zero")
+}
+
+func TestWithDeduplicationStore(t *testing.T) {
+	store := NewMemoryDeduplicationStore(time.Minute)
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithDeduplicationStore(store),
+		WithNoRetry(),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, store, conf.EventsAPI.Deduplication)
+	assert.True(t, conf.EventsAPI.NoRetry)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithDeduplicationStore(nil),
+	})
+	assert.EqualError(t, err, "deduplication store cannot be nil")
+}
+
+func TestWithThreadedResponses(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithThreadedResponses(),
+		WithReplyBroadcast(),
+	})
+
+	require.NoError(t, err)
+	assert.True(t, conf.ThreadedResponses)
+	assert.True(t, conf.ReplyBroadcast)
+}
+
+func TestWithInteractionsPath(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithInteractionsPath("/slack/interactions"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "/slack/interactions", conf.EventsAPI.InteractionsPath)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithInteractionsPath("slack/interactions"),
+	})
+	assert.EqualError(t, err, "interactions path must start with a slash")
+}
+
+func TestWithSlashCommandsPath(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithSlashCommandsPath("/slack/commands"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "/slack/commands", conf.EventsAPI.SlashCommandsPath)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithSlashCommandsPath("slack/commands"),
+	})
+	assert.EqualError(t, err, "slash commands path must start with a slash")
+}
+
+func TestWithReceiveEditedMessages(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithReceiveEditedMessages(),
+	})
+
+	require.NoError(t, err)
+	assert.True(t, conf.ReceiveEditedMessages)
+}
+
+func TestWithUserCache(t *testing.T) {
+	cache := NewUserCache(10, time.Minute)
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithUserCache(cache),
+		WithUserCachePrewarming(time.Hour),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, cache, conf.UserCache)
+	assert.True(t, conf.PrewarmUserCache)
+	assert.Equal(t, time.Hour, conf.UserCacheRefreshInterval)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithUserCache(nil),
+	})
+	assert.EqualError(t, err, "user cache must not be nil")
+}
+
+func TestWithMessageMetadata(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithMessageMetadata(),
+	})
+
+	require.NoError(t, err)
+	assert.True(t, conf.MessageMetadata)
+}
+
+func TestWithRateLimit(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithRateLimit(10*time.Second, 100),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitConfig{Enabled: true, MaxWait: 10 * time.Second, MaxQueue: 100}, conf.RateLimit)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithRateLimit(0, 100),
+	})
+	assert.EqualError(t, err, "rate limit max wait must be positive")
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithRateLimit(time.Second, -1),
+	})
+	assert.EqualError(t, err, "rate limit queue length must not be negative")
+}
+
+func TestWithOutboundQueue(t *testing.T) {
+	store := NewJoeMemoryOutboundStore(newTestMemory())
+	var called bool
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithOutboundQueue(store),
+		WithDeadLetterHandler(func(OutboundMessage, error) { called = true }),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, store, conf.OutboundQueue.Store)
+	require.NotNil(t, conf.OutboundQueue.DeadLetter)
+	conf.OutboundQueue.DeadLetter(OutboundMessage{}, nil)
+	assert.True(t, called)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithOutboundQueue(nil),
+	})
+	assert.EqualError(t, err, "outbound store must not be nil")
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithDeadLetterHandler(nil),
+	})
+	assert.EqualError(t, err, "dead letter handler must not be nil")
+}
+
+func TestWithLongMessages(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithMessageSplitting(0),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, LongMessageConfig{Mode: LongMessagesSplit}, conf.LongMessages)
+
+	conf, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithLongMessagesAsSnippet(10000),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, LongMessageConfig{Mode: LongMessagesSnippet, MaxLength: 10000}, conf.LongMessages)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithMessageSplitting(10),
+	})
+	assert.EqualError(t, err, "maximum message length must be at least 100 characters")
+}
+
+func TestWithEventSampling(t *testing.T) {
+	sink := NewEventSinkWriter(new(bytes.Buffer))
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithEventSampling(sink, 0.1),
+	})
+
+	require.NoError(t, err)
+	assert.NotNil(t, conf.EventSampling.Sink)
+	assert.Equal(t, 0.1, conf.EventSampling.Rate)
+	assert.False(t, conf.EventSampling.AllEvents)
+
+	conf, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithRawEventSampling(sink, 1),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, conf.EventSampling.Rate)
+	assert.True(t, conf.EventSampling.AllEvents)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithEventSampling(nil, 1),
+	})
+	assert.EqualError(t, err, "event sink must not be nil")
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{
+		WithEventSampling(sink, 0),
+	})
+	assert.EqualError(t, err, "event sampling rate must be greater than 0 and at most 1")
+}
+
+func TestWithInstallationStore(t *testing.T) {
+	store := NewMemoryInstallationStore()
+	conf, err := newConf("", joeConf(t), []Option{
+		WithInstallationStore(store),
+		WithOAuth("client-id", "client-secret", "chat:write", "channels:history"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, store, conf.EventsAPI.Installations)
+	assert.Equal(t, OAuthConfig{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Scopes:       []string{"chat:write", "channels:history"},
+	}, conf.EventsAPI.OAuth)
+
+	_, err = newConf("", joeConf(t), []Option{
+		WithInstallationStore(nil),
+	})
+	assert.EqualError(t, err, "installation store must not be nil")
+
+	_, err = newConf("", joeConf(t), []Option{
+		WithOAuth("client-id", "", "chat:write"),
+	})
+	assert.EqualError(t, err, "OAuth client ID and secret must not be empty")
+
+	_, err = newConf("", joeConf(t), []Option{
+		WithOAuth("client-id", "client-secret"),
+	})
+	assert.EqualError(t, err, "at least one OAuth scope is required")
+}
+
+func TestWithFilter(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithAllowedChannels("#general", "C1"),
+		WithAllowedChannels("C2"),
+		WithDeniedChannels("#random"),
+		WithAllowedUsers("@jd"),
+		WithDenyExternalUsers(),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, FilterConfig{
+		AllowedChannels:   []string{"#general", "C1", "C2"},
+		DeniedChannels:    []string{"#random"},
+		AllowedUsers:      []string{"@jd"},
+		DenyExternalUsers: true,
+	}, conf.Filter)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{WithAllowedChannels()})
+	assert.EqualError(t, err, "at least one allowed channel is required")
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{WithDeniedChannels()})
+	assert.EqualError(t, err, "at least one denied channel is required")
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{WithAllowedUsers()})
+	assert.EqualError(t, err, "at least one allowed user is required")
+}
+
+func TestWithBotMessages(t *testing.T) {
+	conf, err := newConf("my-secret-token", joeConf(t), []Option{
+		WithIgnoreBotMessages("B1", "A1"),
+		WithBotLoopDetection(5, time.Minute),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, BotMessageConfig{
+		Ignore:      true,
+		AllowedBots: []string{"B1", "A1"},
+		MaxReplies:  5,
+		Window:      time.Minute,
+	}, conf.BotMessages)
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{WithBotLoopDetection(0, time.Minute)})
+	assert.EqualError(t, err, "maximum number of replies to bots must be positive")
+
+	_, err = newConf("my-secret-token", joeConf(t), []Option{WithBotLoopDetection(5, -time.Minute)})
+	assert.EqualError(t, err, "bot loop detection window must not be negative")
+}
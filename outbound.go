@@ -0,0 +1,429 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-joe/joe"
+	"go.uber.org/zap"
+)
+
+// Default settings of the outbound queue.
+const (
+	defaultOutboundMinBackoff = time.Second
+	defaultOutboundMaxBackoff = 5 * time.Minute
+)
+
+// permanentSlackErrors contains the errors of the Slack API that will not go
+// away if the message is retried later.
+var permanentSlackErrors = map[string]bool{
+	"channel_not_found":    true,
+	"not_in_channel":       true,
+	"is_archived":          true,
+	"msg_too_long":         true,
+	"no_text":              true,
+	"too_many_attachments": true,
+	"invalid_blocks":       true,
+	"restricted_action":    true,
+	"invalid_auth":         true,
+	"not_authed":           true,
+	"account_inactive":     true,
+	"token_revoked":        true,
+	"missing_scope":        true,
+}
+
+// An OutboundMessage is a message that waits in the outbound queue until it
+// has been sent successfully.
+type OutboundMessage struct {
+	ID      string    `json:"id"` // defines the order in which messages are sent
+	Channel string    `json:"channel"`
+	Text    string    `json:"text"`
+	Created time.Time `json:"created"`
+}
+
+// An OutboundStore persists the messages of the outbound queue so they are not
+// lost if the bot is restarted before they could be sent.
+type OutboundStore interface {
+	// Add stores a new message.
+	Add(msg OutboundMessage) error
+
+	// Pending returns all stored messages ordered by their ID.
+	Pending() ([]OutboundMessage, error)
+
+	// Remove deletes the message with the given ID.
+	Remove(id string) error
+}
+
+// outboundQueue sends messages asynchronously and retries them with an
+// exponential backoff until they have been sent or failed permanently.
+// Messages to the same channel are sent in the order they were queued.
+type outboundQueue struct {
+	ctx        context.Context
+	store      OutboundStore
+	send       func(text, channel string) error
+	deadLetter func(OutboundMessage, error)
+	logger     *zap.Logger
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+	jitter     func() float64 // returns a random number in [0,1)
+
+	enqueueMu sync.Mutex // makes sure messages are dispatched in the order of their IDs
+
+	mu       sync.Mutex
+	seq      int64
+	channels map[string][]OutboundMessage // pending messages of channels that have a running worker
+}
+
+func newOutboundQueue(ctx context.Context, conf OutboundQueueConfig, send func(text, channel string) error, logger *zap.Logger) *outboundQueue {
+	q := &outboundQueue{
+		ctx:        ctx,
+		store:      conf.Store,
+		send:       send,
+		deadLetter: conf.DeadLetter,
+		logger:     logger,
+		minBackoff: conf.MinBackoff,
+		maxBackoff: conf.MaxBackoff,
+		sleep:      sleep,
+		jitter:     rand.Float64,
+		seq:        time.Now().UnixNano(),
+		channels:   map[string][]OutboundMessage{},
+	}
+
+	if q.minBackoff == 0 {
+		q.minBackoff = defaultOutboundMinBackoff
+	}
+
+	if q.maxBackoff == 0 {
+		q.maxBackoff = defaultOutboundMaxBackoff
+	}
+
+	if q.deadLetter == nil {
+		q.deadLetter = func(msg OutboundMessage, err error) {
+			logger.Error("Dropping message that could not be sent",
+				zap.String("channel", msg.Channel),
+				zap.String("msg_id", msg.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return q
+}
+
+// start sends all messages that are still pending from a previous run.
+func (q *outboundQueue) start() error {
+	pending, err := q.store.Pending()
+	if err != nil {
+		return fmt.Errorf("failed to load pending messages: %w", err)
+	}
+
+	if len(pending) > 0 {
+		q.logger.Info("Sending pending messages of outbound queue",
+			zap.Int("messages", len(pending)),
+		)
+	}
+
+	for _, msg := range pending {
+		if seq, err := strconv.ParseInt(msg.ID, 10, 64); err == nil && seq >= q.seq {
+			q.seq = seq + 1
+		}
+		q.dispatch(msg)
+	}
+
+	return nil
+}
+
+// enqueue stores a new message and schedules it to be sent.
+func (q *outboundQueue) enqueue(text, channel string) error {
+	q.enqueueMu.Lock()
+	defer q.enqueueMu.Unlock()
+
+	q.mu.Lock()
+	q.seq++
+	msg := OutboundMessage{
+		ID:      fmt.Sprintf("%020d", q.seq),
+		Channel: channel,
+		Text:    text,
+		Created: time.Now(),
+	}
+	q.mu.Unlock()
+
+	err := q.store.Add(msg)
+	if err != nil {
+		return fmt.Errorf("failed to store message in outbound queue: %w", err)
+	}
+
+	q.dispatch(msg)
+	return nil
+}
+
+// dispatch appends the message to the queue of its channel and starts a
+// worker for the channel if there is none yet.
+func (q *outboundQueue) dispatch(msg OutboundMessage) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending, running := q.channels[msg.Channel]
+	q.channels[msg.Channel] = append(pending, msg)
+
+	if !running {
+		go q.work(msg.Channel)
+	}
+}
+
+// work sends all queued messages of the given channel one after another.
+func (q *outboundQueue) work(channel string) {
+	for {
+		q.mu.Lock()
+		pending := q.channels[channel]
+		if len(pending) == 0 {
+			delete(q.channels, channel)
+			q.mu.Unlock()
+			return
+		}
+		msg := pending[0]
+		q.mu.Unlock()
+
+		if !q.deliver(msg) {
+			// The adapter is shutting down. The message stays in the store so
+			// it will be sent when the bot starts again.
+			return
+		}
+
+		q.mu.Lock()
+		q.channels[channel] = q.channels[channel][1:]
+		q.mu.Unlock()
+
+		err := q.store.Remove(msg.ID)
+		if err != nil {
+			q.logger.Error("Failed to remove message from outbound queue",
+				zap.String("msg_id", msg.ID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// deliver sends the message until it succeeds or fails permanently. It returns
+// false if the context of the queue was canceled before that.
+func (q *outboundQueue) deliver(msg OutboundMessage) bool {
+	for attempt := 0; ; attempt++ {
+		err := q.send(msg.Text, msg.Channel)
+		if err == nil {
+			return true
+		}
+
+		if isPermanentError(err) {
+			q.deadLetter(msg, err)
+			return true
+		}
+
+		delay := q.backoff(attempt)
+		q.logger.Warn("Failed to send message, will retry",
+			zap.String("channel", msg.Channel),
+			zap.String("msg_id", msg.ID),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+
+		if q.sleep(q.ctx, delay) != nil {
+			return false
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt. The delay doubles with
+// each attempt and is randomized by up to 50% to avoid that many messages are
+// retried at the same time.
+func (q *outboundQueue) backoff(attempt int) time.Duration {
+	d := q.maxBackoff
+	if attempt < 32 {
+		if exp := q.minBackoff << uint(attempt); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+
+	return d/2 + time.Duration(q.jitter()*float64(d/2))
+}
+
+// isPermanentError returns true if retrying a message that failed with the
+// given error will not help.
+func isPermanentError(err error) bool {
+	if errors.Is(err, ErrUnknownChannel) || errors.Is(err, ErrArchivedChannel) || errors.Is(err, ErrUnknownUser) {
+		return true
+	}
+
+	// The Slack API errors are returned as plain errors with the error code as message.
+	for ; err != nil; err = errors.Unwrap(err) {
+		if permanentSlackErrors[err.Error()] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joeMemoryOutboundStore is an OutboundStore that keeps all messages in a
+// joe.Memory.
+type joeMemoryOutboundStore struct {
+	memory joe.Memory
+}
+
+const outboundKeyPrefix = "slack.outbound."
+
+// NewJoeMemoryOutboundStore returns an OutboundStore that keeps all messages
+// in the given joe.Memory (e.g. a Redis or bolt memory module).
+func NewJoeMemoryOutboundStore(memory joe.Memory) OutboundStore {
+	return &joeMemoryOutboundStore{memory: memory}
+}
+
+func (s *joeMemoryOutboundStore) Add(msg OutboundMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return s.memory.Set(outboundKeyPrefix+msg.ID, data)
+}
+
+func (s *joeMemoryOutboundStore) Pending() ([]OutboundMessage, error) {
+	keys, err := s.memory.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	var messages []OutboundMessage
+	for _, key := range keys {
+		if !strings.HasPrefix(key, outboundKeyPrefix) {
+			continue
+		}
+
+		data, ok, err := s.memory.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		var msg OutboundMessage
+		err = json.Unmarshal(data, &msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode message %q: %w", key, err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+func (s *joeMemoryOutboundStore) Remove(id string) error {
+	_, err := s.memory.Delete(outboundKeyPrefix + id)
+	return err
+}
+
+// fileOutboundStore is an OutboundStore that keeps all messages in a local
+// JSON file.
+type fileOutboundStore struct {
+	path string
+
+	mu       sync.Mutex
+	messages []OutboundMessage
+}
+
+// NewFileOutboundStore returns an OutboundStore that keeps all messages in a
+// JSON file at the given path. If the file exists already, the messages that
+// it contains are sent when the adapter starts.
+func NewFileOutboundStore(path string) (OutboundStore, error) {
+	s := &fileOutboundStore{path: path}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &s.messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode outbound queue file: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *fileOutboundStore) Add(msg OutboundMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, msg)
+	return s.save()
+}
+
+func (s *fileOutboundStore) Pending() ([]OutboundMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := append([]OutboundMessage(nil), s.messages...)
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+
+	return messages, nil
+}
+
+func (s *fileOutboundStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, msg := range s.messages {
+		if msg.ID == id {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			return s.save()
+		}
+	}
+
+	return nil
+}
+
+// save writes all messages to a temporary file which then replaces the actual
+// file so it is never left in a partially written state.
+func (s *fileOutboundStore) save() error {
+	data, err := json.Marshal(s.messages)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,249 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// testSender records all messages that the outbound queue sends. The errors
+// are returned for the first calls in the given order.
+type testSender struct {
+	mu     sync.Mutex
+	errs   []error
+	sent   []string
+	called chan string
+}
+
+func newTestSender(errs ...error) *testSender {
+	return &testSender{errs: errs, called: make(chan string, 100)}
+}
+
+func (s *testSender) send(text, channel string) error {
+	s.mu.Lock()
+	var err error
+	if len(s.errs) > 0 {
+		err, s.errs = s.errs[0], s.errs[1:]
+	}
+	if err == nil {
+		s.sent = append(s.sent, channel+": "+text)
+	}
+	s.mu.Unlock()
+
+	s.called <- text
+	return err
+}
+
+func (s *testSender) waitForCalls(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.called:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout while waiting for call %d", i+1)
+		}
+	}
+}
+
+func (s *testSender) Sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+func newTestOutboundQueue(t *testing.T, store OutboundStore, sender *testSender) (*outboundQueue, *[]time.Duration) {
+	var mu sync.Mutex
+	var sleeps []time.Duration
+
+	conf := OutboundQueueConfig{Store: store}
+	q := newOutboundQueue(context.Background(), conf, sender.send, zaptest.NewLogger(t))
+	q.jitter = func() float64 { return 1 }
+	q.sleep = func(_ context.Context, d time.Duration) error {
+		mu.Lock()
+		sleeps = append(sleeps, d)
+		mu.Unlock()
+		return nil
+	}
+
+	return q, &sleeps
+}
+
+// waitUntilEmpty blocks until the store contains no more pending messages.
+func waitUntilEmpty(t *testing.T, store OutboundStore) {
+	for i := 0; i < 500; i++ {
+		pending, err := store.Pending()
+		require.NoError(t, err)
+		if len(pending) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("timeout while waiting for outbound queue to become empty")
+}
+
+func TestOutboundQueue_RetryInOrder(t *testing.T) {
+	store := NewJoeMemoryOutboundStore(newTestMemory())
+	sender := newTestSender(errors.New("connection refused"), errors.New("internal_error"))
+	q, sleeps := newTestOutboundQueue(t, store, sender)
+
+	require.NoError(t, q.enqueue("first", "C1"))
+	require.NoError(t, q.enqueue("second", "C1"))
+	require.NoError(t, q.enqueue("third", "C1"))
+
+	sender.waitForCalls(t, 5)
+	waitUntilEmpty(t, store)
+
+	assert.Equal(t, []string{"C1: first", "C1: second", "C1: third"}, sender.Sent())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *sleeps)
+}
+
+func TestOutboundQueue_DeadLetter(t *testing.T) {
+	store := NewJoeMemoryOutboundStore(newTestMemory())
+	sender := newTestSender(errors.New("channel_not_found"))
+	q, sleeps := newTestOutboundQueue(t, store, sender)
+
+	var deadLetters []OutboundMessage
+	var deadLetterErr error
+	q.deadLetter = func(msg OutboundMessage, err error) {
+		deadLetters = append(deadLetters, msg)
+		deadLetterErr = err
+	}
+
+	require.NoError(t, q.enqueue("lost", "C1"))
+	require.NoError(t, q.enqueue("delivered", "C1"))
+
+	sender.waitForCalls(t, 2)
+	waitUntilEmpty(t, store)
+
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "lost", deadLetters[0].Text)
+	assert.EqualError(t, deadLetterErr, "channel_not_found")
+	assert.Equal(t, []string{"C1: delivered"}, sender.Sent())
+	assert.Empty(t, *sleeps)
+}
+
+func TestOutboundQueue_SendPendingOnStart(t *testing.T) {
+	store := NewJoeMemoryOutboundStore(newTestMemory())
+	require.NoError(t, store.Add(OutboundMessage{ID: "00000000000000000002", Channel: "C1", Text: "second"}))
+	require.NoError(t, store.Add(OutboundMessage{ID: "00000000000000000001", Channel: "C1", Text: "first"}))
+
+	sender := newTestSender()
+	q, _ := newTestOutboundQueue(t, store, sender)
+	q.seq = 0
+
+	require.NoError(t, q.start())
+	require.NoError(t, q.enqueue("third", "C1"))
+
+	sender.waitForCalls(t, 3)
+	waitUntilEmpty(t, store)
+	assert.Equal(t, []string{"C1: first", "C1: second", "C1: third"}, sender.Sent())
+}
+
+func TestOutboundQueue_Shutdown(t *testing.T) {
+	store := NewJoeMemoryOutboundStore(newTestMemory())
+	sender := newTestSender(errors.New("connection refused"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := newOutboundQueue(ctx, OutboundQueueConfig{Store: store}, sender.send, zaptest.NewLogger(t))
+	cancel()
+
+	require.NoError(t, q.enqueue("Hello", "C1"))
+	sender.waitForCalls(t, 1)
+
+	// The message must remain in the store so it is sent on the next start.
+	pending, err := store.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Hello", pending[0].Text)
+}
+
+func TestOutboundQueue_Backoff(t *testing.T) {
+	q := newOutboundQueue(context.Background(), OutboundQueueConfig{}, nil, zaptest.NewLogger(t))
+
+	q.jitter = func() float64 { return 0 }
+	assert.Equal(t, 500*time.Millisecond, q.backoff(0))
+	assert.Equal(t, 2*time.Second, q.backoff(2))
+
+	q.jitter = func() float64 { return 0.5 }
+	assert.Equal(t, 6*time.Second, q.backoff(3))
+
+	q.jitter = func() float64 { return 1 }
+	assert.Equal(t, 5*time.Minute, q.backoff(20))
+	assert.Equal(t, 5*time.Minute, q.backoff(100))
+}
+
+func TestIsPermanentError(t *testing.T) {
+	assert.True(t, isPermanentError(errors.New("channel_not_found")))
+	assert.True(t, isPermanentError(fmt.Errorf("#foo: %w", ErrUnknownChannel)))
+	assert.True(t, isPermanentError(fmt.Errorf("failed: %w", errors.New("not_in_channel"))))
+	assert.False(t, isPermanentError(errors.New("internal_error")))
+	assert.False(t, isPermanentError(ErrRateLimitMaxWait))
+}
+
+func TestFileOutboundStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbound.json")
+
+	store, err := NewFileOutboundStore(path)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Add(OutboundMessage{ID: "2", Channel: "C1", Text: "second"}))
+	require.NoError(t, store.Add(OutboundMessage{ID: "1", Channel: "C1", Text: "first"}))
+	require.NoError(t, store.Add(OutboundMessage{ID: "3", Channel: "C2", Text: "third"}))
+	require.NoError(t, store.Remove("3"))
+
+	// Open the file again to check the messages have been persisted.
+	store, err = NewFileOutboundStore(path)
+	require.NoError(t, err)
+
+	pending, err := store.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "first", pending[0].Text)
+	assert.Equal(t, "second", pending[1].Text)
+}
+
+func TestJoeMemoryOutboundStore(t *testing.T) {
+	mem := newTestMemory()
+	require.NoError(t, mem.Set("other.key", []byte("foo")))
+	store := NewJoeMemoryOutboundStore(mem)
+
+	require.NoError(t, store.Add(OutboundMessage{ID: "2", Channel: "C1", Text: "second"}))
+	require.NoError(t, store.Add(OutboundMessage{ID: "1", Channel: "C1", Text: "first"}))
+
+	pending, err := store.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "first", pending[0].Text)
+	assert.Equal(t, "second", pending[1].Text)
+
+	require.NoError(t, store.Remove("1"))
+	pending, err = store.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "second", pending[0].Text)
+}
+
+func TestAdapter_SendViaOutboundQueue(t *testing.T) {
+	a, slackAPI := newTestAdapter(t)
+	store := NewJoeMemoryOutboundStore(newTestMemory())
+	a.outbound = newOutboundQueue(a.context, OutboundQueueConfig{Store: store}, a.sendNow, a.logger)
+
+	sent := make(chan bool)
+	expectPostMessage(slackAPI, a.context, "C1H9RESGL", 4).
+		Run(func(mock.Arguments) { sent <- true }).
+		Return("C1H9RESGL", "1360782400.498405", nil)
+
+	require.NoError(t, a.Send("Hello World", "C1H9RESGL"))
+	<-sent
+	waitUntilEmpty(t, store)
+	slackAPI.AssertExpectations(t)
+}
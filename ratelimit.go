@@ -0,0 +1,423 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// Errors that are returned if the rate limiter (see WithRateLimit(…)) rejects
+// a request to the Slack API.
+var (
+	ErrRateLimitQueueFull = errors.New("too many requests are waiting for the rate limiter")
+	ErrRateLimitMaxWait   = errors.New("rate limit wait time exceeds the configured maximum")
+)
+
+const (
+	// defaultRateLimitMaxWait is the maximum time a request waits for the rate
+	// limiter if no other value is configured.
+	defaultRateLimitMaxWait = time.Minute
+
+	// maxRateLimitRetries is the number of times a request is retried if Slack
+	// responded that we exceeded the rate limit.
+	maxRateLimitRetries = 3
+
+	// maxIdleBuckets is the number of token buckets after which buckets that
+	// are not in use anymore are removed.
+	maxIdleBuckets = 1000
+)
+
+// A rateLimit describes how many requests are allowed per second and how many
+// requests may be sent at once.
+type rateLimit struct {
+	rate  float64 // requests per second
+	burst float64
+}
+
+// The rate limit tiers of the Slack Web API.
+// See https://api.slack.com/docs/rate-limits
+var (
+	rateLimitTier1 = rateLimit{rate: 1.0 / 60, burst: 1}
+	rateLimitTier2 = rateLimit{rate: 20.0 / 60, burst: 20}
+	rateLimitTier3 = rateLimit{rate: 50.0 / 60, burst: 50}
+	rateLimitTier4 = rateLimit{rate: 100.0 / 60, burst: 100}
+
+	// chat.postMessage allows roughly one message per second and channel.
+	rateLimitPerChannel = rateLimit{rate: 1, burst: 1}
+)
+
+// methodRateLimits contains the rate limits of all methods that are limited
+// per workspace. Methods that are not listed here are not limited.
+var methodRateLimits = map[string]rateLimit{
+	"chat.update":        rateLimitTier3,
+	"chat.delete":        rateLimitTier3,
+	"chat.postEphemeral": rateLimitTier4,
+	"reactions.add":      rateLimitTier3,
+	"reactions.remove":   rateLimitTier2,
+	"users.info":         rateLimitTier4,
+	"users.list":         rateLimitTier2,
+	"conversations.info": rateLimitTier3,
+	"conversations.list": rateLimitTier2,
+	"conversations.open": rateLimitTier3,
+	"files.upload":       rateLimitTier2,
+	"files.info":         rateLimitTier4,
+	"bots.info":          rateLimitTier3,
+}
+
+// channelRateLimits contains the rate limits of all methods that are limited
+// per channel.
+var channelRateLimits = map[string]rateLimit{
+	"chat.postMessage": rateLimitPerChannel,
+}
+
+// tokenBucket implements the token bucket algorithm. Reservations can drive
+// the number of tokens below zero, in which case the caller has to wait until
+// the bucket has been refilled.
+type tokenBucket struct {
+	limit        rateLimit
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time // set if Slack responded with a rate limit error
+}
+
+func newTokenBucket(limit rateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: limit.burst, last: now}
+}
+
+// reserve takes a token from the bucket and returns how long the caller has
+// to wait until it may use it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.advance(now)
+	b.tokens--
+
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.limit.rate * float64(time.Second))
+	}
+
+	if blocked := b.blockedUntil.Sub(now); blocked > delay {
+		delay = blocked
+	}
+
+	return delay
+}
+
+// cancel returns a reserved token to the bucket.
+func (b *tokenBucket) cancel() {
+	b.tokens++
+	if b.tokens > b.limit.burst {
+		b.tokens = b.limit.burst
+	}
+}
+
+func (b *tokenBucket) advance(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens += elapsed * b.limit.rate
+	if b.tokens > b.limit.burst {
+		b.tokens = b.limit.burst
+	}
+	b.last = now
+}
+
+// idle returns true if the bucket is full and not blocked so it can be
+// removed without changing the behavior of the rate limiter.
+func (b *tokenBucket) idle(now time.Time) bool {
+	b.advance(now)
+	return b.tokens >= b.limit.burst && !now.Before(b.blockedUntil)
+}
+
+// rateLimiter delays requests to the Slack API so they do not exceed the
+// documented rate limits of the Slack API methods.
+type rateLimiter struct {
+	maxWait  time.Duration
+	maxQueue int
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	waiting int
+}
+
+func newRateLimiter(conf RateLimitConfig) *rateLimiter {
+	if conf.MaxWait == 0 {
+		conf.MaxWait = defaultRateLimitMaxWait
+	}
+
+	return &rateLimiter{
+		maxWait:  conf.MaxWait,
+		maxQueue: conf.MaxQueue,
+		now:      time.Now,
+		sleep:    sleep,
+		buckets:  map[string]*tokenBucket{},
+	}
+}
+
+// wait blocks until the rate limits of the given method (and channel) allow
+// another request.
+func (l *rateLimiter) wait(ctx context.Context, method, channelID string) error {
+	l.mu.Lock()
+
+	now := l.now()
+	buckets := l.bucketsFor(method, channelID, now)
+
+	var delay time.Duration
+	for _, b := range buckets {
+		if d := b.reserve(now); d > delay {
+			delay = d
+		}
+	}
+
+	if delay == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	if delay > l.maxWait || (l.maxQueue > 0 && l.waiting >= l.maxQueue) {
+		for _, b := range buckets {
+			b.cancel()
+		}
+		l.mu.Unlock()
+
+		if delay > l.maxWait {
+			return fmt.Errorf("%s: %w", method, ErrRateLimitMaxWait)
+		}
+		return fmt.Errorf("%s: %w", method, ErrRateLimitQueueFull)
+	}
+
+	l.waiting++
+	l.mu.Unlock()
+
+	err := l.sleep(ctx, delay)
+
+	l.mu.Lock()
+	l.waiting--
+	l.mu.Unlock()
+
+	return err
+}
+
+// pause blocks all requests of the given method (and channel) for the given
+// duration. This is used when Slack responded with a rate limit error.
+func (l *rateLimiter) pause(method, channelID string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for _, b := range l.bucketsFor(method, channelID, now) {
+		b.blockedUntil = now.Add(d)
+	}
+}
+
+func (l *rateLimiter) bucketsFor(method, channelID string, now time.Time) []*tokenBucket {
+	var buckets []*tokenBucket
+	if limit, ok := methodRateLimits[method]; ok {
+		buckets = append(buckets, l.bucket(method, limit, now))
+	}
+
+	if limit, ok := channelRateLimits[method]; ok && channelID != "" {
+		buckets = append(buckets, l.bucket(method+"/"+channelID, limit, now))
+	}
+
+	return buckets
+}
+
+func (l *rateLimiter) bucket(key string, limit rateLimit, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if ok {
+		return b
+	}
+
+	if len(l.buckets) >= maxIdleBuckets {
+		for k, b := range l.buckets {
+			if b.idle(now) {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b = newTokenBucket(limit, now)
+	l.buckets[key] = b
+	return b
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitedAPI wraps a slackAPI so that all requests respect the rate limits
+// of Slack. Requests that are rejected by Slack with a rate limit error are
+// retried after the time Slack asked us to wait.
+type rateLimitedAPI struct {
+	api     slackAPI
+	limiter *rateLimiter
+	logger  *zap.Logger
+}
+
+func newRateLimitedAPI(api slackAPI, conf RateLimitConfig, logger *zap.Logger) *rateLimitedAPI {
+	return &rateLimitedAPI{
+		api:     api,
+		limiter: newRateLimiter(conf),
+		logger:  logger,
+	}
+}
+
+func (r *rateLimitedAPI) do(ctx context.Context, method, channelID string, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := r.limiter.wait(ctx, method, channelID)
+		if err != nil {
+			return err
+		}
+
+		err = call()
+
+		var rateLimited *slack.RateLimitedError
+		if !errors.As(err, &rateLimited) || attempt >= maxRateLimitRetries {
+			return err
+		}
+
+		r.logger.Warn("Slack API rate limit exceeded",
+			zap.String("method", method),
+			zap.String("channel_id", channelID),
+			zap.Duration("retry_after", rateLimited.RetryAfter),
+		)
+
+		r.limiter.pause(method, channelID, rateLimited.RetryAfter)
+	}
+}
+
+func (r *rateLimitedAPI) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	return r.api.AuthTestContext(ctx)
+}
+
+func (r *rateLimitedAPI) PostMessageContext(ctx context.Context, channelID string, opts ...slack.MsgOption) (respChannel, respTimestamp string, err error) {
+	err = r.do(ctx, "chat.postMessage", channelID, func() (err error) {
+		respChannel, respTimestamp, err = r.api.PostMessageContext(ctx, channelID, opts...)
+		return err
+	})
+	return respChannel, respTimestamp, err
+}
+
+func (r *rateLimitedAPI) UpdateMessageContext(ctx context.Context, channelID, timestamp string, opts ...slack.MsgOption) (respChannel, respTimestamp, text string, err error) {
+	err = r.do(ctx, "chat.update", channelID, func() (err error) {
+		respChannel, respTimestamp, text, err = r.api.UpdateMessageContext(ctx, channelID, timestamp, opts...)
+		return err
+	})
+	return respChannel, respTimestamp, text, err
+}
+
+func (r *rateLimitedAPI) DeleteMessageContext(ctx context.Context, channelID, timestamp string) (respChannel, respTimestamp string, err error) {
+	err = r.do(ctx, "chat.delete", channelID, func() (err error) {
+		respChannel, respTimestamp, err = r.api.DeleteMessageContext(ctx, channelID, timestamp)
+		return err
+	})
+	return respChannel, respTimestamp, err
+}
+
+func (r *rateLimitedAPI) PostEphemeralContext(ctx context.Context, channelID, userID string, opts ...slack.MsgOption) (timestamp string, err error) {
+	err = r.do(ctx, "chat.postEphemeral", channelID, func() (err error) {
+		timestamp, err = r.api.PostEphemeralContext(ctx, channelID, userID, opts...)
+		return err
+	})
+	return timestamp, err
+}
+
+func (r *rateLimitedAPI) AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	return r.do(ctx, "reactions.add", item.Channel, func() error {
+		return r.api.AddReactionContext(ctx, name, item)
+	})
+}
+
+func (r *rateLimitedAPI) RemoveReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	return r.do(ctx, "reactions.remove", item.Channel, func() error {
+		return r.api.RemoveReactionContext(ctx, name, item)
+	})
+}
+
+func (r *rateLimitedAPI) GetUserInfoContext(ctx context.Context, user string) (u *slack.User, err error) {
+	err = r.do(ctx, "users.info", "", func() (err error) {
+		u, err = r.api.GetUserInfoContext(ctx, user)
+		return err
+	})
+	return u, err
+}
+
+func (r *rateLimitedAPI) GetUsersContext(ctx context.Context) (users []slack.User, err error) {
+	err = r.do(ctx, "users.list", "", func() (err error) {
+		users, err = r.api.GetUsersContext(ctx)
+		return err
+	})
+	return users, err
+}
+
+func (r *rateLimitedAPI) GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (channel *slack.Channel, err error) {
+	err = r.do(ctx, "conversations.info", "", func() (err error) {
+		channel, err = r.api.GetConversationInfoContext(ctx, channelID, includeLocale)
+		return err
+	})
+	return channel, err
+}
+
+func (r *rateLimitedAPI) GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) (channels []slack.Channel, nextCursor string, err error) {
+	err = r.do(ctx, "conversations.list", "", func() (err error) {
+		channels, nextCursor, err = r.api.GetConversationsContext(ctx, params)
+		return err
+	})
+	return channels, nextCursor, err
+}
+
+func (r *rateLimitedAPI) OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (channel *slack.Channel, noOp, alreadyOpen bool, err error) {
+	err = r.do(ctx, "conversations.open", "", func() (err error) {
+		channel, noOp, alreadyOpen, err = r.api.OpenConversationContext(ctx, params)
+		return err
+	})
+	return channel, noOp, alreadyOpen, err
+}
+
+func (r *rateLimitedAPI) UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (file *slack.File, err error) {
+	err = r.do(ctx, "files.upload", "", func() (err error) {
+		file, err = r.api.UploadFileContext(ctx, params)
+		return err
+	})
+	return file, err
+}
+
+func (r *rateLimitedAPI) GetFileInfoContext(ctx context.Context, fileID string, count, page int) (file *slack.File, comments []slack.Comment, paging *slack.Paging, err error) {
+	err = r.do(ctx, "files.info", "", func() (err error) {
+		file, comments, paging, err = r.api.GetFileInfoContext(ctx, fileID, count, page)
+		return err
+	})
+	return file, comments, paging, err
+}
+
+func (r *rateLimitedAPI) GetBotInfoContext(ctx context.Context, bot string) (b *slack.Bot, err error) {
+	err = r.do(ctx, "bots.info", "", func() (err error) {
+		b, err = r.api.GetBotInfoContext(ctx, bot)
+		return err
+	})
+	return b, err
+}
+
+// GetFile is not rate limited since it downloads files directly instead of
+// calling a method of the Slack API.
+func (r *rateLimitedAPI) GetFile(downloadURL string, writer io.Writer) error {
+	return r.api.GetFile(downloadURL, writer)
+}
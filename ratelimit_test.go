@@ -0,0 +1,140 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// newTestRateLimiter returns a rateLimiter with a fake clock that advances
+// whenever the rate limiter sleeps. All sleep durations are recorded.
+func newTestRateLimiter(conf RateLimitConfig) (*rateLimiter, *[]time.Duration) {
+	now := time.Now()
+	var sleeps []time.Duration
+
+	l := newRateLimiter(conf)
+	l.now = func() time.Time { return now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+		return nil
+	}
+
+	return l, &sleeps
+}
+
+func TestRateLimiter_PerChannel(t *testing.T) {
+	l, sleeps := newTestRateLimiter(RateLimitConfig{})
+	ctx := context.Background()
+
+	require.NoError(t, l.wait(ctx, "chat.postMessage", "C1"))
+	require.NoError(t, l.wait(ctx, "chat.postMessage", "C2"))
+	assert.Empty(t, *sleeps)
+
+	require.NoError(t, l.wait(ctx, "chat.postMessage", "C1"))
+	assert.Equal(t, []time.Duration{time.Second}, *sleeps)
+
+	// Methods without a documented rate limit are not delayed.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, l.wait(ctx, "auth.test", ""))
+	}
+	assert.Len(t, *sleeps, 1)
+}
+
+func TestRateLimiter_Tier(t *testing.T) {
+	l, sleeps := newTestRateLimiter(RateLimitConfig{})
+	ctx := context.Background()
+
+	// Tier 2 allows 20 requests per minute.
+	for i := 0; i < 20; i++ {
+		require.NoError(t, l.wait(ctx, "reactions.remove", "C1"))
+	}
+	assert.Empty(t, *sleeps)
+
+	require.NoError(t, l.wait(ctx, "reactions.remove", "C2"))
+	assert.Equal(t, []time.Duration{3 * time.Second}, *sleeps)
+}
+
+func TestRateLimiter_MaxWait(t *testing.T) {
+	l, sleeps := newTestRateLimiter(RateLimitConfig{MaxWait: 500 * time.Millisecond})
+	ctx := context.Background()
+
+	require.NoError(t, l.wait(ctx, "chat.postMessage", "C1"))
+
+	err := l.wait(ctx, "chat.postMessage", "C1")
+	assert.True(t, errors.Is(err, ErrRateLimitMaxWait))
+	assert.EqualError(t, err, "chat.postMessage: rate limit wait time exceeds the configured maximum")
+	assert.Empty(t, *sleeps)
+
+	// The rejected request must not have used up a token.
+	l.now = func() time.Time { return time.Now().Add(time.Second) }
+	require.NoError(t, l.wait(ctx, "chat.postMessage", "C1"))
+	assert.Empty(t, *sleeps)
+}
+
+func TestRateLimiter_MaxQueue(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{MaxQueue: 1})
+	ctx := context.Background()
+
+	sleeping := make(chan bool)
+	wakeUp := make(chan bool)
+	l.sleep = func(context.Context, time.Duration) error {
+		sleeping <- true
+		<-wakeUp
+		return nil
+	}
+
+	require.NoError(t, l.wait(ctx, "chat.postMessage", "C1"))
+
+	done := make(chan error)
+	go func() { done <- l.wait(ctx, "chat.postMessage", "C1") }()
+	<-sleeping
+
+	err := l.wait(ctx, "chat.postMessage", "C1")
+	assert.True(t, errors.Is(err, ErrRateLimitQueueFull))
+
+	close(wakeUp)
+	assert.NoError(t, <-done)
+}
+
+func TestRateLimitedAPI_Retry(t *testing.T) {
+	ctx := context.Background()
+	client := new(mockSlack)
+
+	expectPostMessage(client, ctx, "C1", 1).
+		Return("", "", &slack.RateLimitedError{RetryAfter: 5 * time.Second}).Once()
+	expectPostMessage(client, ctx, "C1", 1).
+		Return("C1", "1360782400.498405", nil).Once()
+
+	api := newRateLimitedAPI(client, RateLimitConfig{}, zaptest.NewLogger(t))
+	limiter, sleeps := newTestRateLimiter(RateLimitConfig{})
+	api.limiter = limiter
+
+	_, ts, err := api.PostMessageContext(ctx, "C1", slack.MsgOptionText("Hello", false))
+	require.NoError(t, err)
+	assert.Equal(t, "1360782400.498405", ts)
+	assert.Equal(t, []time.Duration{5 * time.Second}, *sleeps)
+	client.AssertExpectations(t)
+}
+
+func TestRateLimitedAPI_RetryAfterExceedsMaxWait(t *testing.T) {
+	ctx := context.Background()
+	client := new(mockSlack)
+
+	expectPostMessage(client, ctx, "C1", 1).
+		Return("", "", &slack.RateLimitedError{RetryAfter: time.Hour}).Once()
+
+	api := newRateLimitedAPI(client, RateLimitConfig{}, zaptest.NewLogger(t))
+	limiter, _ := newTestRateLimiter(RateLimitConfig{})
+	api.limiter = limiter
+
+	_, _, err := api.PostMessageContext(ctx, "C1", slack.MsgOptionText("Hello", false))
+	assert.True(t, errors.Is(err, ErrRateLimitMaxWait))
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,241 @@
+package slack
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultSamplesPerMinute is the maximum number of event samples per minute if
+// no other value is configured.
+const defaultSamplesPerMinute = 10
+
+// redactedValue replaces the values of all sensitive fields of sampled events.
+const redactedValue = "[REDACTED]"
+
+// redactedFields contains the JSON keys whose values are removed from sampled
+// events because they may contain secrets or personal data. Additionally all
+// keys that contain "token" or "secret" are redacted.
+var redactedFields = map[string]bool{
+	"text":                    true,
+	"fallback":                true,
+	"pretext":                 true,
+	"title":                   true,
+	"name":                    true,
+	"label":                   true,
+	"value":                   true,
+	"email":                   true,
+	"phone":                   true,
+	"real_name":               true,
+	"real_name_normalized":    true,
+	"display_name":            true,
+	"display_name_normalized": true,
+	"first_name":              true,
+	"last_name":               true,
+	"response_url":            true,
+	"url_private":             true,
+	"url_private_download":    true,
+	"preview":                 true,
+	"trigger_id":              true,
+}
+
+// An EventSample contains the redacted payload of an event that the adapter
+// received from Slack.
+type EventSample struct {
+	Time    time.Time       `json:"time"`
+	Type    string          `json:"type"`
+	Unknown bool            `json:"unknown"` // true if the adapter does not support this type of event
+	Payload json.RawMessage `json:"payload"`
+}
+
+// An EventSink receives the event samples. It must be safe for concurrent use.
+type EventSink func(EventSample)
+
+// NewEventSinkWriter returns an EventSink that writes all samples as JSON lines
+// to the given writer (e.g. a file or os.Stderr). Errors of the writer are
+// ignored.
+func NewEventSinkWriter(w io.Writer) EventSink {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+
+	return func(sample EventSample) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(sample)
+	}
+}
+
+// eventSampler passes a random sample of event payloads to an EventSink. The
+// number of samples per minute is limited so the sink is not flooded if Slack
+// suddenly sends many unknown events.
+type eventSampler struct {
+	conf   EventSamplingConfig
+	logger *zap.Logger
+	random func() float64
+	now    func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	samples     int
+}
+
+// newEventSampler returns a new eventSampler or nil if event sampling is
+// disabled. All methods of the eventSampler can be called on a nil value.
+func newEventSampler(conf EventSamplingConfig, logger *zap.Logger) *eventSampler {
+	if conf.Sink == nil {
+		return nil
+	}
+
+	if conf.Rate == 0 {
+		conf.Rate = 1
+	}
+
+	if conf.MaxPerMinute == 0 {
+		conf.MaxPerMinute = defaultSamplesPerMinute
+	}
+
+	return &eventSampler{
+		conf:   conf,
+		logger: logger,
+		random: rand.Float64,
+		now:    time.Now,
+	}
+}
+
+// sampleUnknown samples the payload of an event that the adapter does not
+// support. It does nothing if all raw events are sampled already.
+func (s *eventSampler) sampleUnknown(eventType string, payload interface{}) {
+	if s == nil || s.conf.AllEvents {
+		return
+	}
+
+	s.sample(eventType, true, payload)
+}
+
+// sampleRaw samples the payload of any event before it is processed. It does
+// nothing unless sampling of all events is enabled.
+func (s *eventSampler) sampleRaw(eventType string, payload interface{}) {
+	if s == nil || !s.conf.AllEvents {
+		return
+	}
+
+	s.sample(eventType, false, payload)
+}
+
+func (s *eventSampler) sample(eventType string, unknown bool, payload interface{}) {
+	if !s.take() {
+		return
+	}
+
+	data, err := redactPayload(payload)
+	if err != nil {
+		s.logger.Debug("Failed to sample event",
+			zap.String("type", eventType),
+			zap.Error(err),
+		)
+		return
+	}
+
+	s.conf.Sink(EventSample{
+		Time:    s.now(),
+		Type:    eventType,
+		Unknown: unknown,
+		Payload: data,
+	})
+}
+
+// take decides if the next event should be sampled.
+func (s *eventSampler) take() bool {
+	if s.random() >= s.conf.Rate {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.windowStart) >= time.Minute {
+		s.windowStart = now
+		s.samples = 0
+	}
+
+	if s.samples >= s.conf.MaxPerMinute {
+		return false
+	}
+
+	s.samples++
+	return true
+}
+
+// redactPayload encodes the payload as JSON and replaces the values of all
+// sensitive fields.
+func redactPayload(payload interface{}) (json.RawMessage, error) {
+	var data []byte
+	switch p := payload.(type) {
+	case json.RawMessage:
+		data = p
+	case []byte:
+		data = p
+	default:
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(redact(v))
+}
+
+func redact(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for key, val := range x {
+			if _, ok := val.(string); ok && isRedactedField(key) {
+				x[key] = redactedValue
+			} else {
+				x[key] = redact(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range x {
+			x[i] = redact(val)
+		}
+	}
+
+	return v
+}
+
+func isRedactedField(key string) bool {
+	key = strings.ToLower(key)
+	return redactedFields[key] || strings.Contains(key, "token") || strings.Contains(key, "secret")
+}
+
+// payloadType returns the type of the inner event of an Events API request or
+// the type of the request itself if it has no inner event.
+func payloadType(body []byte) string {
+	var payload struct {
+		Type  string `json:"type"`
+		Event struct {
+			Type string `json:"type"`
+		} `json:"event"`
+	}
+
+	_ = json.Unmarshal(body, &payload)
+	if payload.Event.Type != "" {
+		return payload.Event.Type
+	}
+
+	return payload.Type
+}
@@ -0,0 +1,252 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// testEventSink records all samples that it receives.
+type testEventSink struct {
+	mu      sync.Mutex
+	samples []EventSample
+}
+
+func (s *testEventSink) sink(sample EventSample) {
+	s.mu.Lock()
+	s.samples = append(s.samples, sample)
+	s.mu.Unlock()
+}
+
+func (s *testEventSink) Samples() []EventSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]EventSample(nil), s.samples...)
+}
+
+func TestEventSampler_Redact(t *testing.T) {
+	sink := new(testEventSink)
+	s := newEventSampler(EventSamplingConfig{Sink: sink.sink}, zaptest.NewLogger(t))
+
+	s.sampleUnknown("test", map[string]interface{}{
+		"type":  "test",
+		"token": "xoxb-secret",
+		"user":  "U1234",
+		"text":  "Hello World",
+		"blocks": []interface{}{
+			map[string]interface{}{
+				"type": "section",
+				"text": map[string]interface{}{"type": "mrkdwn", "text": "Secret"},
+			},
+		},
+		"profile": map[string]interface{}{
+			"email":         "jd@example.com",
+			"bot_token":     "xoxb-secret",
+			"client_secret": "secret",
+		},
+	})
+
+	samples := sink.Samples()
+	require.Len(t, samples, 1)
+	assert.Equal(t, "test", samples[0].Type)
+	assert.True(t, samples[0].Unknown)
+	assert.JSONEq(t, `{
+		"type": "test",
+		"token": "[REDACTED]",
+		"user": "U1234",
+		"text": "[REDACTED]",
+		"blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": "[REDACTED]"}}],
+		"profile": {
+			"email": "[REDACTED]",
+			"bot_token": "[REDACTED]",
+			"client_secret": "[REDACTED]"
+		}
+	}`, string(samples[0].Payload))
+}
+
+func TestEventSampler_RedactAttachments(t *testing.T) {
+	sink := new(testEventSink)
+	s := newEventSampler(EventSamplingConfig{Sink: sink.sink, AllEvents: true}, zaptest.NewLogger(t))
+
+	s.sampleRaw("message", json.RawMessage(`{
+		"type": "message",
+		"user": "U1234",
+		"attachments": [{
+			"id": 1,
+			"color": "danger",
+			"fallback": "Deployment of billing failed",
+			"pretext": "Deployment report",
+			"title": "billing v1.2.3",
+			"text": "Error: connection refused",
+			"fields": [{"title": "Customer", "value": "ACME Corp.", "short": true}],
+			"actions": [{"name": "retry", "text": "Retry", "type": "button", "value": "billing"}]
+		}],
+		"blocks": [{
+			"type": "input",
+			"label": "Reason",
+			"element": {"type": "plain_text_input", "action_id": "reason"}
+		}]
+	}`))
+
+	samples := sink.Samples()
+	require.Len(t, samples, 1)
+	assert.JSONEq(t, `{
+		"type": "message",
+		"user": "U1234",
+		"attachments": [{
+			"id": 1,
+			"color": "danger",
+			"fallback": "[REDACTED]",
+			"pretext": "[REDACTED]",
+			"title": "[REDACTED]",
+			"text": "[REDACTED]",
+			"fields": [{"title": "[REDACTED]", "value": "[REDACTED]", "short": true}],
+			"actions": [{"name": "[REDACTED]", "text": "[REDACTED]", "type": "button", "value": "[REDACTED]"}]
+		}],
+		"blocks": [{
+			"type": "input",
+			"label": "[REDACTED]",
+			"element": {"type": "plain_text_input", "action_id": "reason"}
+		}]
+	}`, string(samples[0].Payload))
+}
+
+func TestEventSampler_RawPayload(t *testing.T) {
+	sink := new(testEventSink)
+	s := newEventSampler(EventSamplingConfig{Sink: sink.sink, AllEvents: true}, zaptest.NewLogger(t))
+
+	s.sampleUnknown("ignored", []byte(`{}`))
+	s.sampleRaw("message", json.RawMessage(`{"type":"message","text":"Hello"}`))
+	s.sampleRaw("invalid", []byte(`{`))
+
+	samples := sink.Samples()
+	require.Len(t, samples, 1)
+	assert.Equal(t, "message", samples[0].Type)
+	assert.False(t, samples[0].Unknown)
+	assert.JSONEq(t, `{"type":"message","text":"[REDACTED]"}`, string(samples[0].Payload))
+}
+
+func TestEventSampler_Limits(t *testing.T) {
+	sink := new(testEventSink)
+	s := newEventSampler(EventSamplingConfig{Sink: sink.sink, Rate: 0.5, MaxPerMinute: 2}, zaptest.NewLogger(t))
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	random := 0.4
+	s.random = func() float64 { return random }
+
+	s.sampleUnknown("a", []byte(`{}`))
+	s.sampleUnknown("b", []byte(`{}`))
+	s.sampleUnknown("c", []byte(`{}`)) // exceeds MaxPerMinute
+
+	now = now.Add(time.Minute)
+	s.sampleUnknown("d", []byte(`{}`))
+
+	random = 0.5
+	s.sampleUnknown("e", []byte(`{}`)) // not sampled by rate
+
+	var types []string
+	for _, sample := range sink.Samples() {
+		types = append(types, sample.Type)
+	}
+
+	assert.Equal(t, []string{"a", "b", "d"}, types)
+}
+
+func TestEventSampler_Disabled(t *testing.T) {
+	s := newEventSampler(EventSamplingConfig{}, zaptest.NewLogger(t))
+	require.Nil(t, s)
+
+	// Calling the sampler must not panic if it is disabled.
+	s.sampleUnknown("test", []byte(`{}`))
+	s.sampleRaw("test", []byte(`{}`))
+}
+
+func TestNewEventSinkWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewEventSinkWriter(&buf)
+
+	ts := time.Date(2020, 7, 25, 12, 0, 0, 0, time.UTC)
+	sink(EventSample{Time: ts, Type: "a", Payload: json.RawMessage(`{"type":"a"}`)})
+	sink(EventSample{Time: ts, Type: "b", Unknown: true, Payload: json.RawMessage(`{"type":"b"}`)})
+
+	assert.Equal(t, `{"time":"2020-07-25T12:00:00Z","type":"a","unknown":false,"payload":{"type":"a"}}
+{"time":"2020-07-25T12:00:00Z","type":"b","unknown":true,"payload":{"type":"b"}}
+`, buf.String())
+}
+
+func TestAdapter_SampleUnknownEvents(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	sink := new(testEventSink)
+	a.sampler = newEventSampler(EventSamplingConfig{Sink: sink.sink}, a.logger)
+
+	type unknownEvent struct{ ID, Text string }
+	processTestEvents(t, a, unknownEvent{"42", "Hello"})
+
+	// processTestEvents does not set the type of the slackEvent.
+	samples := sink.Samples()
+	require.Len(t, samples, 1)
+	assert.JSONEq(t, `{"ID":"42","Text":"[REDACTED]"}`, string(samples[0].Payload))
+}
+
+func TestEventsAPIServer_LogAndSampleUnknownEvents(t *testing.T) {
+	obs, logs := observer.New(zap.ErrorLevel)
+	sink := new(testEventSink)
+	s, recordedEvents := newTestEventsAPIServer(t, Config{
+		Logger:                 zap.New(obs),
+		LogUnknownMessageTypes: true,
+		EventSampling:          EventSamplingConfig{Sink: sink.sink},
+	})
+
+	// The pin_added event is known to the slackevents package but not
+	// supported by the adapter.
+	req := httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+		Type: slackevents.CallbackEvent,
+		InnerEvent: rawJSON(map[string]string{
+			"type":    "pin_added",
+			"user":    "U1234",
+			"channel": "C1H9RESGL",
+		}),
+	}))
+
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// Events of unknown types cannot be parsed at all.
+	req = httptest.NewRequest("POST", "/", toJSON(slackevents.EventsAPICallbackEvent{
+		Type:       slackevents.CallbackEvent,
+		Token:      "secret",
+		InnerEvent: rawJSON(map[string]string{"type": "new_event_type"}),
+	}))
+
+	resp = httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+
+	assert.Empty(t, recordedEvents())
+
+	messages := logs.TakeAll()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Received unknown event type", messages[0].Message)
+	assert.Equal(t, "pin_added", messages[0].ContextMap()["type"])
+	assert.Equal(t, "Failed to parse slack event", messages[1].Message)
+
+	samples := sink.Samples()
+	require.Len(t, samples, 2)
+	assert.Equal(t, "pin_added", samples[0].Type)
+	assert.Equal(t, "new_event_type", samples[1].Type)
+	assert.Contains(t, string(samples[1].Payload), `"token":"[REDACTED]"`)
+}
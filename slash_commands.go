@@ -0,0 +1,144 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// slashCommandResponseTimeout is the timeout for sending a response to the
+// response URL of a slash command.
+const slashCommandResponseTimeout = 10 * time.Second
+
+// The SlashCommandEvent is emitted when a user invokes one of the slash
+// commands of your Slack app (e.g. "/deploy billing"). The request has already
+// been acknowledged when the event is emitted, so handlers should use the
+// Respond(…) or RespondInChannel(…) functions to answer the user. Slack accepts
+// up to five responses within 30 minutes after the command was invoked.
+//
+// See https://api.slack.com/interactivity/slash-commands
+type SlashCommandEvent struct {
+	Command      string // the command including the leading slash (e.g. "/deploy")
+	Text         string // all text after the command
+	UserID       string
+	ChannelID    string
+	TriggerID    string // can be used to open a modal
+	ResponseURL  string
+	TeamID       string
+	EnterpriseID string
+	Data         *slack.SlashCommand
+}
+
+// Respond sends a message to the response URL of the slash command that is
+// only visible to the user who invoked the command.
+func (e SlashCommandEvent) Respond(text string) error {
+	return e.RespondWithMessage(context.Background(), slack.Msg{
+		ResponseType: slack.ResponseTypeEphemeral,
+		Text:         text,
+	})
+}
+
+// RespondInChannel sends a message to the response URL of the slash command
+// that is visible to all members of the channel in which the command was invoked.
+func (e SlashCommandEvent) RespondInChannel(text string) error {
+	return e.RespondWithMessage(context.Background(), slack.Msg{
+		ResponseType: slack.ResponseTypeInChannel,
+		Text:         text,
+	})
+}
+
+// RespondWithMessage sends the given message to the response URL of the slash
+// command. This can be used to respond with Block Kit blocks or to replace
+// a previous response.
+func (e SlashCommandEvent) RespondWithMessage(ctx context.Context, msg slack.Msg) error {
+	if e.ResponseURL == "" {
+		return fmt.Errorf("slash command %q has no response URL", e.Command)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, slashCommandResponseTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.ResponseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send slash command response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send slash command response: unexpected HTTP status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// newSlashCommandEvent creates the slackEvent for the given slash command.
+func newSlashCommandEvent(cmd *slack.SlashCommand) slackEvent {
+	return slackEvent{
+		Type:         "slash_command",
+		Data:         cmd,
+		TeamID:       cmd.TeamID,
+		EnterpriseID: cmd.EnterpriseID,
+	}
+}
+
+// handleSlashCommand emits the joe event for the slash command.
+func (a *BotAdapter) handleSlashCommand(cmd *slack.SlashCommand, brain joe.EventEmitter) {
+	brain.Emit(SlashCommandEvent{
+		Command:      cmd.Command,
+		Text:         cmd.Text,
+		UserID:       cmd.UserID,
+		ChannelID:    cmd.ChannelID,
+		TriggerID:    cmd.TriggerID,
+		ResponseURL:  cmd.ResponseURL,
+		TeamID:       a.teamID,
+		EnterpriseID: a.enterpriseID,
+		Data:         cmd,
+	})
+}
+
+// slashCommandsHandler handles the HTTP requests that Slack sends when users
+// invoke a slash command. The request is acknowledged with an empty response
+// and handlers respond asynchronously via the response URL.
+func (a *EventsAPIServer) slashCommandsHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := a.readRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// The body was already consumed to verify the signature so we have to
+	// restore it before the form can be parsed.
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	cmd, err := slack.SlashCommandParse(r)
+	if err != nil {
+		a.logger.Error("Failed to parse slash command", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !a.verifyToken(cmd.Token) {
+		a.logger.Warn("Rejected slash command with invalid verification token")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	a.enqueue(newSlashCommandEvent(&cmd), w)
+}
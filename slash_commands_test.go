@@ -0,0 +1,146 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func slashCommandRequest(path string, form url.Values) *http.Request {
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestEventsAPIServer_SlashCommand(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t, Config{
+		EventsAPI: EventsAPIConfig{SlashCommandsPath: "/slack/commands"},
+	})
+
+	form := url.Values{
+		"token":        {s.verificationToken},
+		"command":      {"/deploy"},
+		"text":         {"billing v1.2.3"},
+		"user_id":      {"U1234"},
+		"channel_id":   {"C1H9RESGL"},
+		"trigger_id":   {"13345224609.738474920.8088930838d88f008e0"},
+		"response_url": {"https://hooks.slack.com/commands/1234/5678"},
+	}
+
+	resp := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(resp, slashCommandRequest("/slack/commands", form))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Body.String())
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+	require.IsType(t, SlashCommandEvent{}, events[0])
+
+	actual := events[0].(SlashCommandEvent)
+	assert.Equal(t, "/deploy", actual.Command)
+	assert.Equal(t, "billing v1.2.3", actual.Text)
+	assert.Equal(t, "U1234", actual.UserID)
+	assert.Equal(t, "C1H9RESGL", actual.ChannelID)
+	assert.Equal(t, "13345224609.738474920.8088930838d88f008e0", actual.TriggerID)
+	assert.Equal(t, "https://hooks.slack.com/commands/1234/5678", actual.ResponseURL)
+	require.NotNil(t, actual.Data)
+	assert.Equal(t, "/deploy", actual.Data.Command)
+}
+
+func TestEventsAPIServer_SlashCommandInvalidToken(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t, Config{
+		EventsAPI: EventsAPIConfig{SlashCommandsPath: "/slack/commands"},
+	})
+
+	form := url.Values{"token": {"wrong"}, "command": {"/deploy"}}
+
+	resp := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(resp, slashCommandRequest("/slack/commands", form))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Empty(t, recordedEvents())
+}
+
+func TestSocketModeClient_SlashCommand(t *testing.T) {
+	f := newFakeSocketMode(t)
+	acked := make(chan bool)
+	f.connections <- func(conn *websocket.Conn) {
+		payload, err := json.Marshal(slack.SlashCommand{
+			Command:   "/oncall",
+			Text:      "who",
+			UserID:    "U1234",
+			ChannelID: "C1H9RESGL",
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, conn.WriteJSON(socketModeEnvelope{
+			Type:       "slash_commands",
+			EnvelopeID: "1",
+			Payload:    payload,
+		}))
+
+		var ack socketModeAck
+		require.NoError(t, conn.ReadJSON(&ack))
+		assert.Equal(t, "1", ack.EnvelopeID)
+
+		close(acked)
+		f.waitForClose(conn)
+	}
+
+	brain, recordedEvents := newTestSocketModeClient(t, f)
+	waitForEvents(t, brain, 1)
+	<-acked
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+	require.IsType(t, SlashCommandEvent{}, events[0])
+
+	actual := events[0].(SlashCommandEvent)
+	assert.Equal(t, "/oncall", actual.Command)
+	assert.Equal(t, "who", actual.Text)
+	assert.Equal(t, "U1234", actual.UserID)
+}
+
+func TestSlashCommandEvent_Respond(t *testing.T) {
+	var received []slack.Msg
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var msg slack.Msg
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		received = append(received, msg)
+	}))
+	defer srv.Close()
+
+	evt := SlashCommandEvent{Command: "/deploy", ResponseURL: srv.URL}
+	require.NoError(t, evt.Respond("Only for you"))
+	require.NoError(t, evt.RespondInChannel("For everyone"))
+
+	require.Len(t, received, 2)
+	assert.Equal(t, slack.ResponseTypeEphemeral, received[0].ResponseType)
+	assert.Equal(t, "Only for you", received[0].Text)
+	assert.Equal(t, slack.ResponseTypeInChannel, received[1].ResponseType)
+	assert.Equal(t, "For everyone", received[1].Text)
+}
+
+func TestSlashCommandEvent_RespondError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	evt := SlashCommandEvent{Command: "/deploy", ResponseURL: srv.URL}
+	err := evt.Respond("Hello")
+	assert.EqualError(t, err, "failed to send slash command response: unexpected HTTP status code 404")
+
+	evt = SlashCommandEvent{Command: "/deploy"}
+	err = evt.Respond("Hello")
+	assert.EqualError(t, err, `slash command "/deploy" has no response URL`)
+}
@@ -0,0 +1,461 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"go.uber.org/zap"
+)
+
+// SocketModeClient is an adapter that receives messages from Slack using Socket
+// Mode. Like the EventsAPIServer it receives Events API payloads but instead of
+// requiring a public HTTP endpoint, it opens a WebSocket connection to Slack.
+//
+// See https://api.slack.com/apis/connections/socket
+type SocketModeClient struct {
+	*BotAdapter
+	appToken       string
+	apiURL         string
+	http           *http.Client
+	dialer         *websocket.Dialer
+	reconnectDelay time.Duration
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+
+	writeMu sync.Mutex     // a WebSocket connection supports only one writer
+	pending sync.WaitGroup // interactions that have not been acknowledged yet
+
+	queue       chan slackEvent // acknowledged events waiting to be processed
+	queuePolicy QueuePolicy
+	queueDone   chan struct{} // closed when the queue has been processed
+
+	stop      chan struct{} // closed when the client is closed
+	done      chan struct{} // closed when the connection loop has returned
+	closeOnce sync.Once
+}
+
+// socketModeEnvelope is the message format Slack uses for all messages that
+// are sent via a Socket Mode connection.
+type socketModeEnvelope struct {
+	Type                   string          `json:"type"`
+	EnvelopeID             string          `json:"envelope_id"`
+	Payload                json.RawMessage `json:"payload"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload"`
+	Reason                 string          `json:"reason"` // only set on disconnect envelopes
+}
+
+// socketModeAck is used to acknowledge a socketModeEnvelope.
+type socketModeAck struct {
+	EnvelopeID string      `json:"envelope_id"`
+	Payload    interface{} `json:"payload,omitempty"`
+}
+
+// SocketModeAdapter returns a new SocketModeClient as joe.Module.
+// The appToken is an app-level token (starting with "xapp-") with the
+// connections:write scope and the botToken is the usual bot user token that is
+// used to send messages.
+func SocketModeAdapter(appToken, botToken string, opts ...Option) joe.Module {
+	return joe.ModuleFunc(func(joeConf *joe.Config) error {
+		conf, err := newConf(botToken, joeConf, opts)
+		if err != nil {
+			return err
+		}
+		conf.AppToken = appToken
+
+		a, err := NewSocketModeClient(joeConf.Context, conf)
+		if err != nil {
+			return err
+		}
+
+		joeConf.SetAdapter(a)
+		return nil
+	})
+}
+
+// NewSocketModeClient creates a new *SocketModeClient that connects to Slack
+// using Socket Mode. Note that you will usually configure this type of slack
+// adapter as joe.Module (i.e. using the SocketModeAdapter function of this package).
+//
+// You need to close the adapter if it has been created without error in order
+// to release the WebSocket connection to Slack.
+func NewSocketModeClient(ctx context.Context, conf Config) (*SocketModeClient, error) {
+	if conf.AppToken == "" {
+		return nil, errors.New("socket mode requires an app-level token")
+	}
+
+	events := make(chan slackEvent)
+	client := slack.New(conf.Token, conf.slackOptions()...)
+	adapter, err := newAdapter(ctx, client, nil, events, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	queueSize := conf.EventsAPI.QueueSize
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	a := &SocketModeClient{
+		BotAdapter:     adapter,
+		appToken:       conf.AppToken,
+		apiURL:         conf.apiURL(),
+		http:           http.DefaultClient,
+		dialer:         websocket.DefaultDialer,
+		reconnectDelay: time.Second,
+		queue:          make(chan slackEvent, queueSize),
+		queuePolicy:    conf.EventsAPI.QueuePolicy,
+		queueDone:      make(chan struct{}),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	// Start managing the WebSocket connection and forwarding the received
+	// events. These goroutines are stopped when the adapter is closed in
+	// SocketModeClient.Close().
+	go a.run()
+	go a.processQueue()
+
+	return a, nil
+}
+
+func (a *SocketModeClient) run() {
+	defer close(a.done)
+
+	for {
+		err := a.connect()
+		if err == nil {
+			err = a.readEnvelopes()
+		}
+
+		select {
+		case <-a.stop:
+			return
+		default:
+		}
+
+		if err == nil {
+			// Slack asked us to reconnect so we do this immediately.
+			continue
+		}
+
+		a.logger.Error("Socket Mode connection failure",
+			zap.Error(err),
+			zap.Duration("reconnect_delay", a.reconnectDelay),
+		)
+
+		select {
+		case <-a.stop:
+			return
+		case <-time.After(a.reconnectDelay):
+		}
+	}
+}
+
+// connect requests a new WebSocket URL from the Slack API and then dials it.
+func (a *SocketModeClient) connect() error {
+	wsURL, err := a.openConnection()
+	if err != nil {
+		return fmt.Errorf("failed to open socket mode connection: %w", err)
+	}
+
+	conn, resp, err := a.dialer.DialContext(a.context, wsURL, nil)
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to dial socket mode URL: %w", err)
+	}
+
+	a.connMu.Lock()
+	defer a.connMu.Unlock()
+
+	select {
+	case <-a.stop:
+		// The adapter was closed while we were connecting.
+		_ = conn.Close()
+		return errors.New("adapter is closed")
+	default:
+	}
+
+	a.conn = conn
+	a.logger.Debug("Connected to Slack via Socket Mode")
+
+	return nil
+}
+
+// openConnection calls the apps.connections.open API method to retrieve the
+// URL of a new Socket Mode WebSocket connection.
+func (a *SocketModeClient) openConnection() (string, error) {
+	req, err := http.NewRequestWithContext(a.context, http.MethodPost, a.apiURL+"apps.connections.open", http.NoBody)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+a.appToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status code %d", resp.StatusCode)
+	}
+
+	var r struct {
+		slack.SlackResponse
+		URL string `json:"url"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !r.Ok {
+		return "", fmt.Errorf("slack API error: %s", r.Error)
+	}
+
+	return r.URL, nil
+}
+
+// readEnvelopes processes all messages from the current connection until the
+// connection fails or Slack sends a "disconnect" envelope, in which case the
+// function returns nil to signal that the client should reconnect.
+func (a *SocketModeClient) readEnvelopes() error {
+	a.connMu.Lock()
+	conn := a.conn
+	a.connMu.Unlock()
+
+	defer conn.Close()
+
+	for {
+		var env socketModeEnvelope
+		err := conn.ReadJSON(&env)
+		if err != nil {
+			return fmt.Errorf("failed to read from socket mode connection: %w", err)
+		}
+
+		a.sampler.sampleRaw(env.Type, env.Payload)
+
+		switch env.Type {
+		case "hello":
+			a.logger.Debug("Received Socket Mode hello message")
+
+		case "disconnect":
+			a.logger.Info("Slack requested to reconnect Socket Mode connection",
+				zap.String("reason", env.Reason),
+			)
+			return nil
+
+		case "events_api":
+			a.ack(conn, env, nil)
+			a.handleEventsAPIPayload(env.Payload)
+
+		case "interactive":
+			a.handleInteractivePayload(conn, env)
+
+		case "slash_commands":
+			a.ack(conn, env, nil)
+			a.handleSlashCommandPayload(env.Payload)
+
+		default:
+			if env.EnvelopeID != "" {
+				a.ack(conn, env, nil)
+			}
+
+			a.sampler.sampleUnknown(env.Type, env.Payload)
+
+			if a.logUnknownMessageTypes {
+				a.logger.Error("Received unknown Socket Mode envelope type",
+					zap.String("type", env.Type),
+					zap.ByteString("payload", env.Payload),
+				)
+			}
+		}
+	}
+}
+
+// ack acknowledges the given envelope, optionally with a response payload.
+func (a *SocketModeClient) ack(conn *websocket.Conn, env socketModeEnvelope, payload interface{}) {
+	a.writeMu.Lock()
+	err := conn.WriteJSON(socketModeAck{
+		EnvelopeID: env.EnvelopeID,
+		Payload:    payload,
+	})
+	a.writeMu.Unlock()
+
+	if err != nil {
+		a.logger.Error("Failed to acknowledge Socket Mode envelope",
+			zap.String("envelope_id", env.EnvelopeID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (a *SocketModeClient) handleEventsAPIPayload(payload json.RawMessage) {
+	// The WebSocket connection is authenticated via the app token so there is
+	// no verification token that we would need to check here.
+	eventsAPIEvent, err := slackevents.ParseEvent(payload, slackevents.OptionNoVerifyToken())
+	if err != nil {
+		a.sampler.sampleUnknown(payloadType(payload), payload)
+		a.logger.Error("Failed to parse slack event", zap.Error(err))
+		return
+	}
+
+	if eventsAPIEvent.Type != slackevents.CallbackEvent {
+		a.logger.Error("Received unknown top level event type",
+			zap.String("type", eventsAPIEvent.Type),
+		)
+		return
+	}
+
+	evt, ok := a.eventsAPIEvent(eventsAPIEvent.InnerEvent)
+	if ok {
+		a.emit(evt)
+	}
+}
+
+// handleInteractivePayload processes the payload of an interactive component.
+// View submissions are only acknowledged after the joe handlers had a chance to
+// set a response. Waiting for the handlers happens in another goroutine so the
+// connection can be read in the meantime.
+func (a *SocketModeClient) handleInteractivePayload(conn *websocket.Conn, env socketModeEnvelope) {
+	var callback slack.InteractionCallback
+	err := json.Unmarshal(env.Payload, &callback)
+	if err != nil {
+		a.ack(conn, env, nil)
+		a.logger.Error("Failed to parse interaction payload", zap.Error(err))
+		return
+	}
+
+	evt, resp := newInteractionEvent(&callback)
+	if resp == nil {
+		a.ack(conn, env, nil)
+		a.emit(evt)
+		return
+	}
+
+	a.pending.Add(1)
+	go func() {
+		defer a.pending.Done()
+		if !a.emit(evt) {
+			a.ack(conn, env, nil)
+			return
+		}
+
+		a.ack(conn, env, resp.wait(interactionResponseTimeout))
+	}()
+}
+
+func (a *SocketModeClient) handleSlashCommandPayload(payload json.RawMessage) {
+	var cmd slack.SlashCommand
+	err := json.Unmarshal(payload, &cmd)
+	if err != nil {
+		a.logger.Error("Failed to parse slash command payload", zap.Error(err))
+		return
+	}
+
+	a.emit(newSlashCommandEvent(&cmd))
+}
+
+// emit adds the event to the queue of events that are waiting to be processed
+// so a slow handler does not delay reading and acknowledging new envelopes.
+// If the queue is full, the event is dropped if the QueuePolicyDrop is
+// configured. Otherwise emit waits until there is room in the queue. Since
+// envelopes are acknowledged before they are queued, the QueuePolicyReject
+// behaves like the QueuePolicyBlock. The function returns true if the event
+// was queued.
+func (a *SocketModeClient) emit(evt slackEvent) bool {
+	select {
+	case a.queue <- evt:
+		a.logger.Debug("Queued slack event",
+			zap.String("type", evt.Type),
+			zap.Int("queue_depth", len(a.queue)),
+		)
+		return true
+	default:
+	}
+
+	logger := a.logger.With(
+		zap.String("type", evt.Type),
+		zap.Int("queue_depth", len(a.queue)),
+		zap.Int("queue_size", cap(a.queue)),
+	)
+
+	if a.queuePolicy == QueuePolicyDrop {
+		logger.Warn("Dropping slack event because event queue is full")
+		return false
+	}
+
+	logger.Warn("Event queue is full, waiting for event processing")
+
+	select {
+	case a.queue <- evt:
+		return true
+	case <-a.stop:
+		// The event was acknowledged already so it is lost.
+		logger.Error("Dropping slack event because client is shutting down")
+		return false
+	}
+}
+
+// processQueue forwards all queued events to the event processing loop until
+// the client is closed.
+func (a *SocketModeClient) processQueue() {
+	defer close(a.queueDone)
+	for evt := range a.queue {
+		select {
+		case a.events <- evt:
+		case <-a.stop:
+			// The queue is closed once the connection loop has returned so we
+			// can drain it without blocking the shutdown.
+			dropped := 1
+			for range a.queue {
+				dropped++
+			}
+
+			a.logger.Error("Dropped queued slack events during shutdown", zap.Int("events", dropped))
+			return
+		}
+	}
+}
+
+// Close disconnects the adapter from Slack. It is safe to call Close more than
+// once.
+func (a *SocketModeClient) Close() error {
+	a.closeOnce.Do(a.close)
+	return nil
+}
+
+func (a *SocketModeClient) close() {
+	a.connMu.Lock()
+	close(a.stop)
+	if a.conn != nil {
+		// Closing the connection unblocks the reading goroutine. Any error here
+		// is irrelevant since we are discarding the connection anyway.
+		_ = a.conn.Close()
+	}
+	a.connMu.Unlock()
+
+	// After we are sure we do not get any new events from the WebSocket and
+	// all interactions have been acknowledged, we must stop event processing
+	// loop by closing the channel.
+	<-a.done
+	a.pending.Wait()
+	close(a.queue)
+	<-a.queueDone
+	close(a.events)
+}
@@ -0,0 +1,404 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/go-joe/joe/joetest"
+	"github.com/go-joe/joe/reactions"
+	"github.com/gorilla/websocket"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// compile time test to check if we are implementing the interface.
+var _ joe.Adapter = new(SocketModeClient)
+
+// fakeSocketMode is a local fake of the Slack API that supports the
+// apps.connections.open method and the Socket Mode WebSocket connection.
+type fakeSocketMode struct {
+	*httptest.Server
+	t *testing.T
+
+	// connections receives a function for each new WebSocket connection that
+	// decides what happens on this connection.
+	connections chan func(conn *websocket.Conn)
+
+	mu   sync.Mutex
+	acks []string // envelope IDs
+}
+
+func newFakeSocketMode(t *testing.T) *fakeSocketMode {
+	f := &fakeSocketMode{t: t, connections: make(chan func(*websocket.Conn), 10)}
+	upgrader := websocket.Upgrader{}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth.test":
+			_ = json.NewEncoder(w).Encode(slack.AuthTestResponse{
+				UserID: "test-userID",
+			})
+
+		case "/apps.connections.open":
+			assert.Equal(t, "Bearer xapp-test", r.Header.Get("Authorization"))
+			wsURL := "ws" + strings.TrimPrefix(f.URL, "http") + "/ws"
+			_, _ = w.Write([]byte(`{"ok": true, "url": "` + wsURL + `"}`))
+
+		case "/ws":
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			handle := <-f.connections
+			handle(conn)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(f.Close)
+	return f
+}
+
+// sendEvent writes the given inner event as events_api envelope and waits
+// until the client has acknowledged it.
+func (f *fakeSocketMode) sendEvent(conn *websocket.Conn, envelopeID string, innerEvent interface{}) {
+	payload, err := json.Marshal(slackevents.EventsAPICallbackEvent{
+		Type:       slackevents.CallbackEvent,
+		InnerEvent: rawJSON(innerEvent),
+	})
+	require.NoError(f.t, err)
+
+	err = conn.WriteJSON(socketModeEnvelope{
+		Type:       "events_api",
+		EnvelopeID: envelopeID,
+		Payload:    payload,
+	})
+	require.NoError(f.t, err)
+
+	var ack socketModeAck
+	err = conn.ReadJSON(&ack)
+	require.NoError(f.t, err)
+
+	f.mu.Lock()
+	f.acks = append(f.acks, ack.EnvelopeID)
+	f.mu.Unlock()
+}
+
+// waitForClose blocks until the client closes the connection.
+func (f *fakeSocketMode) waitForClose(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeSocketMode) Acks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acks...)
+}
+
+func newTestSocketModeClient(t *testing.T, f *fakeSocketMode, handlers ...interface{}) (_ *joetest.Brain, finish func() (events []interface{})) {
+	ctx := context.Background()
+	conf := Config{
+		AppToken:    "xapp-test",
+		Logger:      zaptest.NewLogger(t),
+		SlackAPIURL: f.URL,
+	}
+
+	c, err := NewSocketModeClient(ctx, conf)
+	require.NoError(t, err)
+
+	brain := joetest.NewBrain(t)
+	for _, h := range handlers {
+		brain.RegisterHandler(h)
+	}
+
+	done := make(chan bool)
+	go func() {
+		c.handleSlackEvents(brain.Brain)
+		done <- true
+	}()
+
+	finish = func() []interface{} {
+		assert.NoError(t, c.Close())
+		<-done // wait until event processing loop has stopped
+		brain.Finish()
+		return brain.RecordedEvents()
+	}
+
+	return brain, finish
+}
+
+// waitForEvents blocks until the brain has received n events.
+func waitForEvents(t *testing.T, brain *joetest.Brain, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-brain.Events():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout while waiting for event %d", i+1)
+		}
+	}
+}
+
+func TestSocketModeClient_HandleEvents(t *testing.T) {
+	f := newFakeSocketMode(t)
+	acked := make(chan bool)
+	f.connections <- func(conn *websocket.Conn) {
+		require.NoError(t, conn.WriteJSON(socketModeEnvelope{Type: "hello"}))
+
+		f.sendEvent(conn, "1", slackevents.MessageEvent{
+			Type:        slackevents.Message,
+			Channel:     "D023BB3L2",
+			ChannelType: "im",
+			User:        "U1234",
+			Text:        "Hello World!",
+		})
+
+		f.sendEvent(conn, "2", slackevents.AppMentionEvent{
+			Type:    slackevents.AppMention,
+			Channel: "C1H9RESGL",
+			User:    "U1234",
+			Text:    "<@test-userID> ping",
+		})
+
+		f.sendEvent(conn, "3", slackevents.ReactionAddedEvent{
+			Type:     slackevents.ReactionAdded,
+			User:     "U1234",
+			Reaction: "+1",
+			Item: slackevents.Item{
+				Type:      "message",
+				Channel:   "D023BB3L2",
+				Timestamp: "1595070350",
+			},
+		})
+
+		close(acked)
+		f.waitForClose(conn)
+	}
+
+	brain, recordedEvents := newTestSocketModeClient(t, f)
+	waitForEvents(t, brain, 3)
+	<-acked
+
+	events := recordedEvents()
+	require.Len(t, events, 3)
+	assert.Equal(t, []string{"1", "2", "3"}, f.Acks())
+
+	msg, ok := events[0].(joe.ReceiveMessageEvent)
+	require.True(t, ok)
+	assert.Equal(t, "Hello World!", msg.Text)
+	assert.Equal(t, "D023BB3L2", msg.Channel)
+	assert.Equal(t, "U1234", msg.AuthorID)
+	assert.IsType(t, new(slack.MessageEvent), msg.Data)
+
+	msg, ok = events[1].(joe.ReceiveMessageEvent)
+	require.True(t, ok)
+	assert.Equal(t, "ping", msg.Text)
+
+	reaction, ok := events[2].(reactions.Event)
+	require.True(t, ok)
+	assert.Equal(t, "+1", reaction.Reaction.Shortcode)
+	assert.Equal(t, "1595070350", reaction.MessageID)
+}
+
+func TestSocketModeClient_SlowEventProcessing(t *testing.T) {
+	f := newFakeSocketMode(t)
+	acked := make(chan bool)
+	f.connections <- func(conn *websocket.Conn) {
+		// Fail instead of blocking forever if an event is not acknowledged.
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		for _, id := range []string{"1", "2", "3"} {
+			f.sendEvent(conn, id, slackevents.MessageEvent{
+				Type:        slackevents.Message,
+				Channel:     "D023BB3L2",
+				ChannelType: "im",
+				User:        "U1234",
+				Text:        "Hello World!",
+			})
+		}
+
+		close(acked)
+		f.waitForClose(conn)
+	}
+
+	conf := Config{
+		AppToken:    "xapp-test",
+		Logger:      zaptest.NewLogger(t),
+		SlackAPIURL: f.URL,
+		EventsAPI:   EventsAPIConfig{QueueSize: 1},
+	}
+
+	// Nobody is processing the events so only the queue can take them.
+	c, err := NewSocketModeClient(context.Background(), conf)
+	require.NoError(t, err)
+
+	select {
+	case <-acked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout while waiting for acknowledgements")
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, f.Acks())
+	assert.NoError(t, c.Close())
+}
+
+func TestSocketModeClient_Interactive(t *testing.T) {
+	f := newFakeSocketMode(t)
+	acked := make(chan bool)
+	f.connections <- func(conn *websocket.Conn) {
+		payload, err := json.Marshal(slack.InteractionCallback{
+			Type: slack.InteractionTypeShortcut,
+			User: slack.User{ID: "U1234"},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, conn.WriteJSON(socketModeEnvelope{
+			Type:       "interactive",
+			EnvelopeID: "1",
+			Payload:    payload,
+		}))
+
+		var ack socketModeAck
+		require.NoError(t, conn.ReadJSON(&ack))
+		assert.Equal(t, "1", ack.EnvelopeID)
+		assert.Nil(t, ack.Payload)
+
+		close(acked)
+		f.waitForClose(conn)
+	}
+
+	brain, recordedEvents := newTestSocketModeClient(t, f)
+	waitForEvents(t, brain, 1)
+	<-acked
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+	require.IsType(t, ShortcutEvent{}, events[0])
+	assert.Equal(t, "U1234", events[0].(ShortcutEvent).UserID)
+}
+
+func TestSocketModeClient_ViewSubmission(t *testing.T) {
+	f := newFakeSocketMode(t)
+	release := make(chan bool)
+	acked := make(chan bool)
+	f.connections <- func(conn *websocket.Conn) {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		payload, err := json.Marshal(slack.InteractionCallback{
+			Type: slack.InteractionTypeViewSubmission,
+			User: slack.User{ID: "U1234"},
+			View: slack.View{CallbackID: "deploy"},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, conn.WriteJSON(socketModeEnvelope{
+			Type:       "interactive",
+			EnvelopeID: "1",
+			Payload:    payload,
+		}))
+
+		// Other envelopes are acknowledged while the handler is still busy.
+		f.sendEvent(conn, "2", slackevents.MessageEvent{
+			Type:        slackevents.Message,
+			Channel:     "D023BB3L2",
+			ChannelType: "im",
+			User:        "U1234",
+			Text:        "Hello World!",
+		})
+		close(release)
+
+		var ack struct {
+			EnvelopeID string          `json:"envelope_id"`
+			Payload    json.RawMessage `json:"payload"`
+		}
+		require.NoError(t, conn.ReadJSON(&ack))
+		assert.Equal(t, "1", ack.EnvelopeID)
+		assert.JSONEq(t, `{"response_action": "errors", "errors": {"version": "unknown version"}}`, string(ack.Payload))
+
+		close(acked)
+		f.waitForClose(conn)
+	}
+
+	brain, recordedEvents := newTestSocketModeClient(t, f, func(evt ViewSubmissionEvent) {
+		<-release
+		evt.RespondWithErrors(map[string]string{"version": "unknown version"})
+	})
+
+	waitForEvents(t, brain, 2)
+	<-acked
+
+	events := recordedEvents()
+	require.Len(t, events, 2)
+	assert.Equal(t, []string{"2"}, f.Acks())
+}
+
+func TestSocketModeClient_Reconnect(t *testing.T) {
+	f := newFakeSocketMode(t)
+	f.connections <- func(conn *websocket.Conn) {
+		require.NoError(t, conn.WriteJSON(socketModeEnvelope{
+			Type:   "disconnect",
+			Reason: "refresh_requested",
+		}))
+		f.waitForClose(conn)
+	}
+
+	f.connections <- func(conn *websocket.Conn) {
+		f.sendEvent(conn, "1", slackevents.MessageEvent{
+			Type:        slackevents.Message,
+			Channel:     "D023BB3L2",
+			ChannelType: "im",
+			User:        "U1234",
+			Text:        "Hello again",
+		})
+
+		f.waitForClose(conn)
+	}
+
+	brain, recordedEvents := newTestSocketModeClient(t, f)
+	waitForEvents(t, brain, 1)
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, "Hello again", events[0].(joe.ReceiveMessageEvent).Text)
+}
+
+func TestSocketModeClient_ConnectionError(t *testing.T) {
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/auth.test" {
+			_ = json.NewEncoder(w).Encode(slack.AuthTestResponse{UserID: "test-userID"})
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"ok": false, "error": "invalid_auth"}`))
+	}))
+	defer slackAPI.Close()
+
+	conf := Config{AppToken: "xapp-test", SlackAPIURL: slackAPI.URL}
+	c, err := NewSocketModeClient(context.Background(), conf)
+	require.NoError(t, err)
+
+	_, err = c.openConnection()
+	assert.EqualError(t, err, "slack API error: invalid_auth")
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+}
+
+func TestNewSocketModeClient_MissingAppToken(t *testing.T) {
+	_, err := NewSocketModeClient(context.Background(), Config{})
+	assert.EqualError(t, err, "socket mode requires an app-level token")
+}
@@ -0,0 +1,215 @@
+package slack
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// Default settings of the user cache that is used if no custom UserCache is
+// configured via the WithUserCache(…) option.
+const (
+	defaultUserCacheSize = 5000
+	defaultUserCacheTTL  = time.Hour
+)
+
+// A UserCache stores the users that the BotAdapter looked up via the Slack API
+// so they do not have to be requested again for every event.
+type UserCache interface {
+	// Get returns the cached user with the given ID if it exists.
+	Get(userID string) (*slack.User, bool)
+
+	// Add adds the given user to the cache or replaces an existing entry.
+	Add(user *slack.User)
+
+	// Remove removes the user with the given ID from the cache.
+	Remove(userID string)
+}
+
+// UserCacheStats contains the number of lookups of the user cache that could
+// be answered from the cache (hits) and those that required a request to the
+// Slack API (misses).
+type UserCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// userCacheCounters contains the counters of the UserCacheStats. They are
+// accessed atomically. Since the struct is always allocated on its own (see
+// BotAdapter.userStats), the counters are 64-bit aligned even on 32-bit
+// platforms.
+type userCacheCounters struct {
+	hits   uint64
+	misses uint64
+}
+
+// lruUserCache is a UserCache that holds a limited number of users and evicts
+// the least recently used entry if it is full. Entries expire after a TTL so
+// changes of the users are eventually picked up.
+type lruUserCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used entry
+}
+
+type userCacheEntry struct {
+	user       *slack.User
+	expiration time.Time
+}
+
+// NewUserCache returns a UserCache that keeps at most size users for the given
+// TTL. If the cache is full, the least recently used user is evicted.
+func NewUserCache(size int, ttl time.Duration) UserCache {
+	return &lruUserCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (c *lruUserCache) Get(userID string) (*slack.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[userID]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*userCacheEntry)
+	if c.now().After(entry.expiration) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.user, true
+}
+
+func (c *lruUserCache) Add(user *slack.User) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &userCacheEntry{user: user, expiration: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[user.ID]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[user.ID] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+func (c *lruUserCache) Remove(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[userID]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lruUserCache) removeElement(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*userCacheEntry)
+	delete(c.entries, entry.user.ID)
+}
+
+// UserCacheStats returns the number of hits and misses of the user cache.
+func (a *BotAdapter) UserCacheStats() UserCacheStats {
+	return UserCacheStats{
+		Hits:   atomic.LoadUint64(&a.userStats.hits),
+		Misses: atomic.LoadUint64(&a.userStats.misses),
+	}
+}
+
+// slackUser returns the user with the given ID either from the user cache or
+// from the Slack API.
+func (a *BotAdapter) slackUser(userID string) (*slack.User, error) {
+	if user, ok := a.users.Get(userID); ok {
+		atomic.AddUint64(&a.userStats.hits, 1)
+		return user, nil
+	}
+
+	atomic.AddUint64(&a.userStats.misses, 1)
+	user, err := a.slack.GetUserInfoContext(a.context, userID)
+	if err != nil && err.Error() == errUserNotFound {
+		// Users of other organizations in shared channels are not always
+		// visible to the bot. We cache them anyway so we do not request
+		// them again for every event.
+		a.logger.Debug("Cannot look up user of other organization", zap.String("user_id", userID))
+		user, err = unknownUser(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.users.Add(user)
+	return user, nil
+}
+
+// prewarmUserCache adds all users of the workspace to the user cache and then
+// repeats this in the given interval until the context of the adapter is done.
+// If the interval is zero, the users are only loaded once.
+func (a *BotAdapter) prewarmUserCache(interval time.Duration) {
+	for {
+		err := a.loadUsers()
+		if err != nil {
+			a.logger.Error("Failed to load users into user cache", zap.Error(err))
+		}
+
+		if interval <= 0 {
+			return
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-a.context.Done():
+			return
+		}
+	}
+}
+
+// loadUsers adds all users of the workspace to the user cache and remembers
+// their names so they can be resolved without listing all users again.
+func (a *BotAdapter) loadUsers() error {
+	// GetUsersContext pages through the users.list API method and also waits
+	// if we hit the rate limit.
+	users, err := a.slack.GetUsersContext(a.context)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	userNames := make(map[string]string, len(users))
+	for i := range users {
+		a.users.Add(&users[i])
+		if !users[i].Deleted {
+			userNames[users[i].Name] = users[i].ID
+		}
+	}
+
+	a.channels.setUsers(userNames)
+	a.logger.Debug("Loaded users into user cache", zap.Int("users", len(users)))
+	return nil
+}
+
+// See https://api.slack.com/events/user_change
+func (a *BotAdapter) handleUserChangeEvent(ev *slack.UserChangeEvent) {
+	// The event contains the complete new user object so we can use it to
+	// replace the outdated cache entry right away.
+	user := ev.User
+	a.users.Add(&user)
+}
@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestUserCache(t *testing.T) {
+	now := time.Now()
+	c := NewUserCache(2, time.Minute).(*lruUserCache)
+	c.now = func() time.Time { return now }
+
+	_, ok := c.Get("U1")
+	assert.False(t, ok)
+
+	c.Add(&slack.User{ID: "U1", Name: "alice"})
+	c.Add(&slack.User{ID: "U2", Name: "bob"})
+
+	user, ok := c.Get("U1")
+	require.True(t, ok)
+	assert.Equal(t, "alice", user.Name)
+
+	// U2 is the least recently used entry and must be evicted.
+	c.Add(&slack.User{ID: "U3", Name: "carol"})
+	_, ok = c.Get("U2")
+	assert.False(t, ok)
+	_, ok = c.Get("U1")
+	assert.True(t, ok)
+	_, ok = c.Get("U3")
+	assert.True(t, ok)
+
+	// Adding an existing user replaces the entry.
+	c.Add(&slack.User{ID: "U1", Name: "alice2"})
+	user, ok = c.Get("U1")
+	require.True(t, ok)
+	assert.Equal(t, "alice2", user.Name)
+
+	c.Remove("U1")
+	_, ok = c.Get("U1")
+	assert.False(t, ok)
+}
+
+func TestUserCache_TTL(t *testing.T) {
+	now := time.Now()
+	c := NewUserCache(10, time.Minute).(*lruUserCache)
+	c.now = func() time.Time { return now }
+
+	c.Add(&slack.User{ID: "U1"})
+	now = now.Add(time.Minute)
+	_, ok := c.Get("U1")
+	assert.True(t, ok)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("U1")
+	assert.False(t, ok)
+	assert.Empty(t, c.entries)
+}
+
+func TestAdapter_UserChangeEvent(t *testing.T) {
+	a, _ := newTestAdapter(t)
+	a.users.Add(&slack.User{ID: "U1234", Name: "jd"})
+
+	events := processTestEvents(t, a, &slack.UserChangeEvent{
+		Type: "user_change",
+		User: slack.User{ID: "U1234", Name: "john.doe"},
+	})
+
+	assert.Empty(t, events)
+	user, ok := a.users.Get("U1234")
+	require.True(t, ok)
+	assert.Equal(t, "john.doe", user.Name)
+}
+
+func TestAdapter_PrewarmUserCache(t *testing.T) {
+	ctx := context.Background()
+	client := new(mockSlack)
+	client.On("AuthTestContext", ctx).Return(&slack.AuthTestResponse{UserID: "42"}, nil)
+	client.On("GetUsersContext", ctx).Return([]slack.User{
+		{ID: "U1", Name: "alice"},
+		{ID: "U2", Name: "bob"},
+	}, nil)
+
+	conf := Config{Logger: zaptest.NewLogger(t)}
+	a, err := newAdapter(ctx, client, nil, make(chan slackEvent), conf)
+	require.NoError(t, err)
+
+	a.prewarmUserCache(0)
+	client.AssertExpectations(t)
+
+	assert.Equal(t, "alice", a.userByID("U1").Name)
+	assert.Equal(t, "bob", a.userByID("U2").Name)
+	assert.Equal(t, UserCacheStats{Hits: 2}, a.UserCacheStats())
+}
+
+func TestEventsAPIServer_HandleUserChangeEvent(t *testing.T) {
+	s, recordedEvents := newTestEventsAPIServer(t)
+	s.users.Add(&slack.User{ID: "U1234", Name: "jd"})
+
+	req := httptest.NewRequest("POST", "/foo", toJSON(slackevents.EventsAPICallbackEvent{
+		Type: slackevents.CallbackEvent,
+		InnerEvent: rawJSON(slack.UserChangeEvent{
+			Type: "user_change",
+			User: slack.User{ID: "U1234", Name: "john.doe"},
+		}),
+	}))
+
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, recordedEvents())
+
+	user, ok := s.users.Get("U1234")
+	require.True(t, ok)
+	assert.Equal(t, "john.doe", user.Name)
+}
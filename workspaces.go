@@ -0,0 +1,498 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// workspace identifies a workspace by its team ID and, if the workspace belongs
+// to an Enterprise Grid organization, by its enterprise ID.
+type workspace struct {
+	teamID       string
+	enterpriseID string
+}
+
+// workspaces manages the adapters of all workspaces in which the app is
+// installed (see WithInstallationStore). Each adapter uses the bot token and
+// bot user ID of its installation. Adapters are created when the first event
+// of a workspace arrives and are reused for all following events.
+type workspaces struct {
+	parent    *BotAdapter
+	store     InstallationStore
+	newClient func(token string) slackAPI
+	ownToken  bool          // false if the parent adapter has no token of its own
+	ttl       time.Duration // of the workspaces of channels
+	now       func() time.Time
+
+	mu             sync.Mutex
+	adapters       map[workspace]*BotAdapter
+	channels       map[string]channelWorkspace // maps channel IDs to the workspace they belong to
+	channelsPruned time.Time                   // time at which expired channels were removed the last time
+	forgets        uint64                      // incremented whenever cached adapters are removed
+}
+
+type channelWorkspace struct {
+	workspace
+	expiration time.Time
+}
+
+func newWorkspaces(parent *BotAdapter, store InstallationStore, newClient func(token string) slackAPI) *workspaces {
+	return &workspaces{
+		parent:    parent,
+		store:     store,
+		newClient: newClient,
+		ttl:       defaultChannelCacheTTL,
+		now:       time.Now,
+		adapters:  map[workspace]*BotAdapter{},
+		channels:  map[string]channelWorkspace{},
+	}
+}
+
+// adapter returns the adapter for the given workspace. The installation of the
+// workspace is looked up in the InstallationStore if we did not see any events
+// from this workspace before. The lock is not held while the store is queried,
+// so a slow store does not delay the events of all other workspaces.
+func (w *workspaces) adapter(teamID, enterpriseID string) (*BotAdapter, error) {
+	ws := workspace{teamID: teamID, enterpriseID: enterpriseID}
+
+	w.mu.Lock()
+	a, ok := w.adapters[ws]
+	forgets := w.forgets
+	w.mu.Unlock()
+	if ok {
+		return a, nil
+	}
+
+	inst, err := w.store.Find(teamID, enterpriseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find installation of team %q: %w", teamID, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if a, ok := w.adapters[ws]; ok {
+		// Another event of the workspace created the adapter in the meantime.
+		return a, nil
+	}
+
+	a = w.newAdapter(ws, inst)
+	if w.forgets == forgets {
+		// The installation might be outdated if the workspace was installed
+		// again while we queried the store, so it is only cached otherwise.
+		w.adapters[ws] = a
+	}
+
+	return a, nil
+}
+
+// newAdapter creates a new adapter that shares the configuration and caches of
+// the parent adapter but uses the credentials of the given installation.
+func (w *workspaces) newAdapter(ws workspace, inst *Installation) *BotAdapter {
+	enterpriseID := ws.enterpriseID
+	if enterpriseID == "" {
+		enterpriseID = inst.EnterpriseID
+	}
+
+	p := w.parent
+	return &BotAdapter{
+		context:      p.context,
+		logger:       p.logger.With(zap.String("team_id", ws.teamID)),
+		name:         p.name,
+		userID:       inst.BotUserID,
+		teamID:       ws.teamID, // the installation has no team ID if it was installed for the whole enterprise
+		enterpriseID: enterpriseID,
+
+		logUnknownMessageTypes: p.logUnknownMessageTypes,
+		listenPassive:          p.listenPassive,
+		threadedResponses:      p.threadedResponses,
+		replyBroadcast:         p.replyBroadcast,
+		receiveEditedMessages:  p.receiveEditedMessages,
+		messageMetadata:        p.messageMetadata,
+
+		sampler:       p.sampler,
+		filter:        p.filter,
+		botMessages:   p.botMessages,
+		sendMsgParams: p.sendMsgParams,
+		longMessages:  p.longMessages,
+
+		slack:  w.newClient(inst.BotToken),
+		events: p.events,
+
+		// User and file IDs are unique across workspaces but channel names
+		// are not, so each workspace needs its own channel cache.
+		users:       p.users,
+		userStats:   p.userStats,
+		channels:    newChannelCache(defaultChannelCacheTTL),
+		sharedFiles: p.sharedFiles,
+
+		// Messages that are sent via the parent adapter (e.g. responses of joe
+		// handlers) use its outbound queue and are routed to the correct
+		// workspace. Messages sent via the workspace adapter are sent directly.
+		outbound: nil,
+	}
+}
+
+// forget removes the cached adapters of the workspaces of the installation, so
+// a new installation (e.g. with a new bot token) is picked up on the next event.
+func (w *workspaces) forget(inst Installation) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.forgets++
+	for ws := range w.adapters {
+		if ws.enterpriseID != inst.EnterpriseID {
+			continue
+		}
+		if inst.TeamID == "" || ws.teamID == inst.TeamID {
+			delete(w.adapters, ws)
+		}
+	}
+}
+
+// remember stores the workspace of a channel so messages to this channel can be
+// sent with the correct bot token. The workspace is remembered until the TTL
+// expired without any new events in the channel. Expired channels are removed
+// at most once per TTL so they do not accumulate.
+func (w *workspaces) remember(channelID, teamID, enterpriseID string) {
+	if channelID == "" || teamID == "" {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	if now.After(w.channelsPruned.Add(w.ttl)) {
+		for id, cw := range w.channels {
+			if now.After(cw.expiration) {
+				delete(w.channels, id)
+			}
+		}
+		w.channelsPruned = now
+	}
+
+	w.channels[channelID] = channelWorkspace{
+		workspace:  workspace{teamID: teamID, enterpriseID: enterpriseID},
+		expiration: now.Add(w.ttl),
+	}
+}
+
+// lookup returns the workspace of a channel that we have seen in an event
+// within the TTL.
+func (w *workspaces) lookup(channelID string) (workspace, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cw, ok := w.channels[channelID]
+	if !ok {
+		return workspace{}, false
+	}
+
+	if w.now().After(cw.expiration) {
+		delete(w.channels, channelID)
+		return workspace{}, false
+	}
+
+	return cw.workspace, true
+}
+
+// adapterForEvent returns the adapter that should process the given event. If
+// the adapter serves multiple workspaces, this is the adapter of the workspace
+// that sent the event. It returns nil if the event must be ignored because the
+// app is not installed in the workspace.
+func (a *BotAdapter) adapterForEvent(evt slackEvent) *BotAdapter {
+	if a.workspaces == nil || evt.TeamID == "" || evt.TeamID == a.teamID {
+		return a
+	}
+
+	wa, err := a.workspaces.adapter(evt.TeamID, evt.EnterpriseID)
+	if err != nil {
+		a.logger.Error("Ignoring event of unknown workspace",
+			zap.String("type", evt.Type),
+			zap.String("team_id", evt.TeamID),
+			zap.String("enterprise_id", evt.EnterpriseID),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	a.workspaces.remember(eventChannel(evt.Data), evt.TeamID, evt.EnterpriseID)
+	return wa
+}
+
+// senderFor returns the adapter that sends messages to the given channel. The
+// messages to channels of other workspaces are sent by the adapter of their
+// workspace so they carry the user ID of its bot. If the adapter has no token
+// of its own, it cannot send messages itself, so messages to channels whose
+// workspace is unknown are rejected.
+func (a *BotAdapter) senderFor(channelID string) (*BotAdapter, error) {
+	if a.workspaces == nil {
+		return a, nil
+	}
+
+	ws, ok := a.workspaces.lookup(channelID)
+	switch {
+	case ok && ws.teamID != a.teamID:
+		return a.workspaces.adapter(ws.teamID, ws.enterpriseID)
+	case !ok && !a.workspaces.ownToken:
+		return nil, fmt.Errorf("cannot determine workspace of channel %q", channelID)
+	default:
+		return a, nil
+	}
+}
+
+// eventChannel returns the ID of the channel in which an event happened or an
+// empty string if the event is not related to a channel.
+func eventChannel(data interface{}) string {
+	switch ev := data.(type) {
+	case *slack.MessageEvent:
+		return ev.Channel
+	case *slack.ReactionAddedEvent:
+		return ev.Item.Channel
+	case *slack.ReactionRemovedEvent:
+		return ev.Item.Channel
+	case *slack.MemberJoinedChannelEvent:
+		return ev.Channel
+	case *slack.MemberLeftChannelEvent:
+		return ev.Channel
+	case *interactionEvent:
+		return ev.callback.Channel.ID
+	case *slack.SlashCommand:
+		return ev.ChannelID
+	default:
+		return ""
+	}
+}
+
+// callbackWorkspace extracts the team and enterprise ID from the body of an
+// Events API request. The slackevents package does not decode the enterprise
+// ID so we have to do this ourselves.
+func callbackWorkspace(body []byte) (teamID, enterpriseID string) {
+	var callback struct {
+		TeamID       string `json:"team_id"`
+		EnterpriseID string `json:"enterprise_id"`
+	}
+
+	_ = json.Unmarshal(body, &callback)
+	return callback.TeamID, callback.EnterpriseID
+}
+
+// teamRouter is the slackAPI of an adapter that serves multiple workspaces. It
+// sends each request that refers to a channel with the client of the workspace
+// that the channel belongs to. All other requests are sent with the client of
+// the adapter's own token, if there is any.
+type teamRouter struct {
+	workspaces *workspaces
+	fallback   slackAPI // nil if the adapter has no token of its own
+	teamID     string   // the team ID of the fallback client
+}
+
+// client returns the client of the workspace of the given channel.
+func (r *teamRouter) client(channelID string) (slackAPI, error) {
+	ws, ok := r.workspaces.lookup(channelID)
+	if !ok || (ws.teamID == r.teamID && r.fallback != nil) {
+		if r.fallback == nil {
+			return nil, fmt.Errorf("cannot determine workspace of channel %q", channelID)
+		}
+		return r.fallback, nil
+	}
+
+	a, err := r.workspaces.adapter(ws.teamID, ws.enterpriseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.slack, nil
+}
+
+// defaultClient returns the client for requests that cannot be routed to a
+// workspace.
+func (r *teamRouter) defaultClient() (slackAPI, error) {
+	if r.fallback == nil {
+		return nil, errors.New("request cannot be routed to a workspace")
+	}
+
+	return r.fallback, nil
+}
+
+func (r *teamRouter) AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.AuthTestContext(ctx)
+}
+
+func (r *teamRouter) PostMessageContext(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, string, error) {
+	c, err := r.client(channelID)
+	if err != nil {
+		return "", "", err
+	}
+	return c.PostMessageContext(ctx, channelID, opts...)
+}
+
+func (r *teamRouter) UpdateMessageContext(ctx context.Context, channelID, timestamp string, opts ...slack.MsgOption) (string, string, string, error) {
+	c, err := r.client(channelID)
+	if err != nil {
+		return "", "", "", err
+	}
+	return c.UpdateMessageContext(ctx, channelID, timestamp, opts...)
+}
+
+func (r *teamRouter) DeleteMessageContext(ctx context.Context, channelID, timestamp string) (string, string, error) {
+	c, err := r.client(channelID)
+	if err != nil {
+		return "", "", err
+	}
+	return c.DeleteMessageContext(ctx, channelID, timestamp)
+}
+
+func (r *teamRouter) PostEphemeralContext(ctx context.Context, channelID, userID string, opts ...slack.MsgOption) (string, error) {
+	c, err := r.client(channelID)
+	if err != nil {
+		return "", err
+	}
+	return c.PostEphemeralContext(ctx, channelID, userID, opts...)
+}
+
+func (r *teamRouter) AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	c, err := r.client(item.Channel)
+	if err != nil {
+		return err
+	}
+	return c.AddReactionContext(ctx, name, item)
+}
+
+func (r *teamRouter) RemoveReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	c, err := r.client(item.Channel)
+	if err != nil {
+		return err
+	}
+	return c.RemoveReactionContext(ctx, name, item)
+}
+
+func (r *teamRouter) GetUserInfoContext(ctx context.Context, user string) (*slack.User, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetUserInfoContext(ctx, user)
+}
+
+func (r *teamRouter) GetUsersContext(ctx context.Context) ([]slack.User, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetUsersContext(ctx)
+}
+
+func (r *teamRouter) GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error) {
+	c, err := r.client(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetConversationInfoContext(ctx, channelID, includeLocale)
+}
+
+func (r *teamRouter) GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, "", err
+	}
+	return c.GetConversationsContext(ctx, params)
+}
+
+func (r *teamRouter) OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, false, false, err
+	}
+	return c.OpenConversationContext(ctx, params)
+}
+
+func (r *teamRouter) UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error) {
+	var c slackAPI
+	var err error
+	if len(params.Channels) > 0 {
+		c, err = r.client(params.Channels[0])
+	} else {
+		c, err = r.defaultClient()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.UploadFileContext(ctx, params)
+}
+
+func (r *teamRouter) GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return c.GetFileInfoContext(ctx, fileID, count, page)
+}
+
+func (r *teamRouter) GetFile(downloadURL string, writer io.Writer) error {
+	c, err := r.defaultClient()
+	if err != nil {
+		return err
+	}
+	return c.GetFile(downloadURL, writer)
+}
+
+func (r *teamRouter) GetBotInfoContext(ctx context.Context, bot string) (*slack.Bot, error) {
+	c, err := r.defaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBotInfoContext(ctx, bot)
+}
+
+// enableWorkspaces lets the adapter process the events of all workspaces in
+// which the app is installed. Requests to the Slack API are routed to the
+// workspace of the channel they refer to.
+func (a *BotAdapter) enableWorkspaces(store InstallationStore, conf Config) {
+	a.workspaces = newWorkspaces(a, store, func(token string) slackAPI {
+		var client slackAPI = slack.New(token, conf.slackOptions()...)
+		if conf.RateLimit.Enabled {
+			// Slack applies its rate limits per workspace.
+			client = newRateLimitedAPI(client, conf.RateLimit, a.logger)
+		}
+		return client
+	})
+
+	router := &teamRouter{workspaces: a.workspaces, teamID: a.teamID}
+	if conf.Token != "" {
+		a.workspaces.ownToken = true
+		router.fallback = a.slack
+	}
+
+	a.slack = router
+}
+
+// Workspace returns the adapter for the workspace with the given team ID (and
+// enterprise ID, if the workspace belongs to an Enterprise Grid organization).
+// It can be used to send messages to a workspace before the bot received any
+// event from it. The workspace is looked up in the InstallationStore (see
+// WithInstallationStore).
+func (a *BotAdapter) Workspace(teamID, enterpriseID string) (*BotAdapter, error) {
+	if teamID != "" && teamID == a.teamID {
+		return a, nil
+	}
+
+	if a.workspaces == nil {
+		return nil, fmt.Errorf("adapter is not installed in team %q", teamID)
+	}
+
+	return a.workspaces.adapter(teamID, enterpriseID)
+}
@@ -0,0 +1,342 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-joe/joe"
+	"github.com/go-joe/joe/joetest"
+	"github.com/go-joe/joe/reactions"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// newTestWorkspaceAdapter creates an adapter without a token of its own that
+// serves the workspaces in the installation store. The returned map contains
+// the mocked client of each bot token.
+func newTestWorkspaceAdapter(t *testing.T, installations ...Installation) (*BotAdapter, map[string]*mockSlack) {
+	store := NewMemoryInstallationStore()
+	clients := map[string]*mockSlack{}
+	for _, inst := range installations {
+		require.NoError(t, store.Save(inst))
+		clients[inst.BotToken] = new(mockSlack)
+	}
+
+	conf := Config{Logger: zaptest.NewLogger(t)}
+	conf.EventsAPI.Installations = store
+
+	// The adapter must not use its own client without a token.
+	a, err := newAdapter(context.Background(), new(mockSlack), nil, make(chan slackEvent), conf)
+	require.NoError(t, err)
+
+	a.workspaces.newClient = func(token string) slackAPI {
+		return clients[token]
+	}
+
+	return a, clients
+}
+
+// processWorkspaceEvents is like processTestEvents but passes the complete
+// slackEvent including its team ID.
+func processWorkspaceEvents(t *testing.T, a *BotAdapter, events ...slackEvent) []interface{} {
+	brain := joetest.NewBrain(t)
+
+	done := make(chan bool)
+	go func() {
+		a.handleSlackEvents(brain.Brain)
+		done <- true
+	}()
+
+	for _, evt := range events {
+		a.events <- evt
+	}
+
+	close(a.events)
+	<-done
+	brain.Finish()
+
+	return brain.RecordedEvents()
+}
+
+func TestAdapter_Workspaces(t *testing.T) {
+	a, clients := newTestWorkspaceAdapter(t,
+		Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "U1"},
+		Installation{TeamID: "T2", BotToken: "xoxb-2", BotUserID: "U2"},
+	)
+
+	newMessage := func(channel, user, text string) *slack.MessageEvent {
+		return &slack.MessageEvent{Msg: slack.Msg{
+			Channel:   channel,
+			User:      user,
+			Text:      text,
+			Timestamp: "1360782400.498405",
+		}}
+	}
+
+	reaction := &slack.ReactionRemovedEvent{User: "U1234", Reaction: "+1"}
+	reaction.Item.Type = "message"
+	reaction.Item.Channel = "C2"
+	reaction.Item.Timestamp = "1360782400.498405"
+
+	events := processWorkspaceEvents(t, a,
+		slackEvent{TeamID: "T1", Data: newMessage("C1", "U1234", "<@U1> hello")},
+		slackEvent{TeamID: "T2", Data: newMessage("C2", "U2", "<@U2> message from the bot itself")},
+		slackEvent{TeamID: "T2", Data: newMessage("C2", "U1234", "<@U1> wrong bot user"), ChannelType: ConversationTypePublic},
+		slackEvent{TeamID: "T3", Data: newMessage("C3", "U1234", "<@U3> unknown team")},
+		slackEvent{TeamID: "T2", Data: reaction},
+	)
+
+	require.Len(t, events, 2)
+
+	msg, ok := events[0].(joe.ReceiveMessageEvent)
+	require.True(t, ok)
+	assert.Equal(t, "hello", msg.Text)
+	assert.Equal(t, "C1", msg.Channel)
+	assert.Equal(t, "T1", msg.Data.(*slack.MessageEvent).Team)
+
+	assert.Equal(t, ReactionRemovedEvent{
+		Reaction:  reactions.Reaction{Shortcode: "+1"},
+		MessageID: "1360782400.498405",
+		Channel:   "C2",
+		AuthorID:  "U1234",
+		TeamID:    "T2",
+	}, events[1])
+
+	// Responses are sent with the token and bot user of the workspace of the
+	// channel.
+	var values1, values2 url.Values
+	expectPostMessage(clients["xoxb-1"], a.context, "C1", 4).
+		Run(captureMsgValues(t, &values1)).
+		Return("C1", "1360782500.498405", nil)
+	expectPostMessage(clients["xoxb-2"], a.context, "C2", 4).
+		Run(captureMsgValues(t, &values2)).
+		Return("C2", "1360782600.498405", nil)
+
+	assert.NoError(t, a.Send("Hello Team 1", "C1"))
+	assert.NoError(t, a.Send("Hello Team 2", "C2"))
+	assert.Equal(t, "U1", values1.Get("user"))
+	assert.Equal(t, "U2", values2.Get("user"))
+
+	// Without a token of its own, the adapter cannot send messages to
+	// channels of unknown workspaces.
+	err := a.Send("Hello Team 3", "C3")
+	assert.EqualError(t, err, `cannot determine workspace of channel "C3"`)
+
+	err = a.SendEphemeral("Hello Team 3", "C3/1360782400.498405", "U1234")
+	assert.EqualError(t, err, `cannot determine workspace of channel "C3"`)
+
+	err = a.Send("Hello", "#general")
+	assert.EqualError(t, err, `cannot determine workspace of channel "#general"`)
+
+	clients["xoxb-1"].AssertExpectations(t)
+	clients["xoxb-2"].AssertExpectations(t)
+}
+
+func TestAdapter_WorkspacesWithOwnToken(t *testing.T) {
+	ctx := context.Background()
+	client := new(mockSlack)
+	client.On("AuthTestContext", ctx).Return(&slack.AuthTestResponse{UserID: "U1", TeamID: "T1"}, nil)
+
+	store := NewMemoryInstallationStore()
+	require.NoError(t, store.Save(Installation{TeamID: "T2", BotToken: "xoxb-2", BotUserID: "U2"}))
+
+	conf := Config{Token: "xoxb-1", Logger: zaptest.NewLogger(t)}
+	conf.EventsAPI.Installations = store
+
+	a, err := newAdapter(ctx, client, nil, make(chan slackEvent), conf)
+	require.NoError(t, err)
+	assert.Equal(t, "T1", a.teamID)
+
+	client2 := new(mockSlack)
+	a.workspaces.newClient = func(string) slackAPI { return client2 }
+
+	// Events of the adapter's own team do not need an installation.
+	wa := a.adapterForEvent(slackEvent{TeamID: "T1"})
+	assert.Equal(t, a, wa)
+
+	wa = a.adapterForEvent(slackEvent{TeamID: "T2", Data: &slack.MessageEvent{Msg: slack.Msg{Channel: "C2"}}})
+	require.NotNil(t, wa)
+	assert.Equal(t, "U2", wa.userID)
+	assert.Equal(t, "T2", wa.teamID)
+
+	// Requests without a known channel use the adapter's own token.
+	client.On("AddReactionContext", ctx, "+1", slack.NewRefToMessage("C1", "1")).Return(nil)
+	client2.On("AddReactionContext", ctx, "+1", slack.NewRefToMessage("C2", "1")).Return(nil)
+
+	assert.NoError(t, a.React(reactions.Reaction{Shortcode: "+1"}, joe.Message{Channel: "C1", ID: "1"}))
+	assert.NoError(t, a.React(reactions.Reaction{Shortcode: "+1"}, joe.Message{Channel: "C2", ID: "1"}))
+
+	client.AssertExpectations(t)
+	client2.AssertExpectations(t)
+}
+
+func TestBotAdapter_Workspace(t *testing.T) {
+	a, clients := newTestWorkspaceAdapter(t,
+		Installation{EnterpriseID: "E1", BotToken: "xoxb-org", BotUserID: "U1"},
+	)
+
+	wa, err := a.Workspace("T1", "E1")
+	require.NoError(t, err)
+	assert.Equal(t, "U1", wa.userID)
+	assert.Equal(t, "T1", wa.teamID)
+	assert.Equal(t, clients["xoxb-org"], wa.slack)
+
+	// The adapters of the workspaces are cached until they are installed again.
+	wa2, err := a.Workspace("T1", "E1")
+	require.NoError(t, err)
+	assert.True(t, wa == wa2)
+
+	a.workspaces.forget(Installation{EnterpriseID: "E1"})
+	wa2, err = a.Workspace("T1", "E1")
+	require.NoError(t, err)
+	assert.False(t, wa == wa2)
+
+	_, err = a.Workspace("T2", "")
+	assert.True(t, errors.Is(err, ErrInstallationNotFound))
+
+	b, _ := newTestAdapter(t)
+	_, err = b.Workspace("T1", "")
+	assert.EqualError(t, err, `adapter is not installed in team "T1"`)
+}
+
+func TestWorkspaces_ChannelExpiration(t *testing.T) {
+	a, _ := newTestWorkspaceAdapter(t)
+	w := a.workspaces
+
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	w.remember("C1", "T1", "")
+	w.remember("C2", "T2", "E1")
+
+	ws, ok := w.lookup("C2")
+	require.True(t, ok)
+	assert.Equal(t, workspace{teamID: "T2", enterpriseID: "E1"}, ws)
+
+	// Channels without new events expire and are removed when new channels
+	// are remembered.
+	now = now.Add(defaultChannelCacheTTL + time.Second)
+	_, ok = w.lookup("C1")
+	assert.False(t, ok)
+
+	w.remember("C3", "T1", "")
+	assert.Len(t, w.channels, 1)
+	assert.Contains(t, w.channels, "C3")
+}
+
+// slowInstallationStore blocks all lookups of the installation of team "T2"
+// until it is released.
+type slowInstallationStore struct {
+	InstallationStore
+	blocked chan bool
+	release chan bool
+}
+
+func (s *slowInstallationStore) Find(teamID, enterpriseID string) (*Installation, error) {
+	if teamID == "T2" {
+		s.blocked <- true
+		<-s.release
+	}
+	return s.InstallationStore.Find(teamID, enterpriseID)
+}
+
+func TestBotAdapter_WorkspaceSlowStore(t *testing.T) {
+	a, _ := newTestWorkspaceAdapter(t,
+		Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "U1"},
+		Installation{TeamID: "T2", BotToken: "xoxb-2", BotUserID: "U2"},
+	)
+
+	store := &slowInstallationStore{
+		InstallationStore: a.workspaces.store,
+		blocked:           make(chan bool),
+		release:           make(chan bool),
+	}
+	a.workspaces.store = store
+
+	wa1, err := a.Workspace("T1", "")
+	require.NoError(t, err)
+
+	done := make(chan *BotAdapter)
+	go func() {
+		wa2, err := a.Workspace("T2", "")
+		assert.NoError(t, err)
+		done <- wa2
+	}()
+	<-store.blocked
+
+	// Known workspaces are not blocked by the lookup of another workspace.
+	wa, err := a.Workspace("T1", "")
+	require.NoError(t, err)
+	assert.True(t, wa == wa1)
+
+	close(store.release)
+	wa2 := <-done
+	require.NotNil(t, wa2)
+	assert.Equal(t, "U2", wa2.userID)
+
+	wa, err = a.Workspace("T2", "")
+	require.NoError(t, err)
+	assert.True(t, wa == wa2)
+}
+
+func TestEventsAPIServer_Workspaces(t *testing.T) {
+	store := NewMemoryInstallationStore()
+	require.NoError(t, store.Save(Installation{TeamID: "T1", EnterpriseID: "E1", BotToken: "xoxb-1", BotUserID: "U1"}))
+
+	conf := Config{}
+	conf.EventsAPI.Installations = store
+	s, recordedEvents := newTestEventsAPIServer(t, conf)
+
+	body := toJSON(map[string]interface{}{
+		"type":          slackevents.CallbackEvent,
+		"team_id":       "T1",
+		"enterprise_id": "E1",
+		"event": map[string]string{
+			"type":    "message",
+			"channel": "C1",
+			"user":    "U1234",
+			"text":    "<@U1> Hello World!",
+			"ts":      "1595070350.000100",
+		},
+	})
+
+	resp := httptest.NewRecorder()
+	s.httpHandler(resp, httptest.NewRequest("POST", "/", body))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	events := recordedEvents()
+	require.Len(t, events, 1)
+
+	msg, ok := events[0].(joe.ReceiveMessageEvent)
+	require.True(t, ok)
+	assert.Equal(t, "Hello World!", msg.Text)
+	assert.Equal(t, "T1", msg.Data.(*slack.MessageEvent).Team)
+}
+
+func TestAdapter_WorkspacesUserCacheStats(t *testing.T) {
+	a, clients := newTestWorkspaceAdapter(t,
+		Installation{TeamID: "T1", BotToken: "xoxb-1", BotUserID: "U1"},
+	)
+
+	wa, err := a.Workspace("T1", "")
+	require.NoError(t, err)
+
+	clients["xoxb-1"].On("GetUserInfoContext", a.context, "U1234").Return(&slack.User{ID: "U1234"}, nil).Once()
+
+	_, err = wa.slackUser("U1234")
+	require.NoError(t, err)
+	_, err = wa.slackUser("U1234")
+	require.NoError(t, err)
+
+	// Lookups of all workspaces are counted by the parent adapter.
+	assert.Equal(t, UserCacheStats{Hits: 1, Misses: 1}, a.UserCacheStats())
+	assert.Equal(t, a.UserCacheStats(), wa.UserCacheStats())
+}